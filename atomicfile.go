@@ -0,0 +1,53 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 02:39 (EDT)
+// Function: replace files all at once
+
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// write it elsewhere, and move it into place when complete, so no one sees it half written.
+// with excl, fail if the file already exists. prep can adjust the file before anything is written
+func writeFileAtomic(file string, data []byte, perm os.FileMode, excl bool, prep func(*os.File) error) error {
+
+	// a name of our own, in the same directory, so the rename cannot cross filesystems
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	defer os.Remove(name)
+
+	err = f.Chmod(perm)
+	if err == nil && prep != nil {
+		err = prep(f)
+	}
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if !excl {
+		return os.Rename(name, file)
+	}
+
+	// link fails if it already exists
+	err = os.Link(name, file)
+	if le, ok := err.(*os.LinkError); ok {
+		return le.Err
+	}
+	return err
+}
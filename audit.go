@@ -0,0 +1,48 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:48 (EDT)
+// Function: audit trail of watcher decisions
+
+package daemon
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+type auditRecord struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	WatcherPid int       `json:"watcher_pid"`
+	Pid        int       `json:"pid,omitempty"`
+	ExitCode   *int      `json:"exit_code,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Message    string    `json:"exit_message,omitempty"`
+	Restarts   int       `json:"restarts"`
+	Delay      string    `json:"delay,omitempty"`
+}
+
+// append a record to the audit log
+func (o *opts) audit(rec auditRecord) {
+
+	if o.auditLog == "" {
+		return
+	}
+
+	rec.Time = o.clock.Now()
+	rec.WatcherPid = os.Getpid()
+
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+
+	// reopen each time, so the log can be rotated underneath us
+	f, err := os.OpenFile(o.auditLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	f.Write(append(buf, '\n'))
+	f.Close()
+}
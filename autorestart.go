@@ -0,0 +1,78 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:46 (EDT)
+// Function: restart a function, in process, and recover from panics
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+type autoRestartExit struct{}
+
+// AutoRestartExit - panic(AutoRestartExit) inside an AutoRestart function to have it restarted,
+// the in process version of exiting with ExitRestart
+var AutoRestartExit = autoRestartExit{}
+
+// AutoRestart(fn, WithOpts...) - run fn, restarting it if it panics, until it returns
+// the restart delay and limit options (WithRestartBackoff, WithRespawnLimit, etc) apply
+func AutoRestart(fn func(), optfn ...optFunc) {
+
+	opt := newOpts(optfn)
+	back := opt.newBackoff()
+
+	for restarts := 0; ; restarts++ {
+		started := opt.clock.Now()
+		why, stack := runRecover(fn)
+		if why == nil {
+			return
+		}
+
+		if why != AutoRestartExit {
+			opt.logf(LogLevelWarn, "panic: %v\n%s", why, stack)
+		}
+
+		if opt.respawnLimit > 0 && restarts >= opt.respawnLimit {
+			opt.logf(LogLevelError, "restarted %d times, giving up", restarts)
+			return
+		}
+
+		delay := back.next(opt.clock.Now().Sub(started))
+		opt.logf(LogLevelDebug, "restarting in %s", delay)
+		opt.clock.Sleep(delay)
+	}
+}
+
+// set in the main program, with WithPanicRestart
+var panicRestart bool
+
+// Run(fn) - run the main program's fn. with WithPanicRestart, a panic is logged,
+// and we exit with ExitRestart, for the watcher to restart us. otherwise it just calls fn
+func Run(fn func()) {
+
+	if !panicRestart {
+		fn()
+		return
+	}
+
+	if why, stack := runRecover(fn); why != nil {
+		fmt.Fprintf(os.Stderr, "panic: %v\n%s", why, stack)
+		os.Exit(ExitRestart)
+	}
+}
+
+// run fn, returning what it panicked with, if anything, and where
+func runRecover(fn func()) (why interface{}, stack []byte) {
+
+	defer func() {
+		if why = recover(); why != nil {
+			stack = debug.Stack()
+		}
+	}()
+
+	fn()
+	return nil, nil
+}
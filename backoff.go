@@ -0,0 +1,66 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:49 (EDT)
+// Function: restart delay
+
+package daemon
+
+import (
+	"math/rand"
+	"time"
+)
+
+type backoff struct {
+	min    time.Duration
+	max    time.Duration
+	factor float64
+	jitter float64
+	curve  func(int) time.Duration
+	reset  time.Duration // WithRestartBackoffReset, otherwise max
+	cur    time.Duration
+	tries  int
+	rnd    *rand.Rand
+}
+
+func (o *opts) newBackoff() *backoff {
+	return &backoff{
+		min:    o.restartDelay,
+		max:    o.restartMax,
+		factor: o.restartFactor,
+		jitter: o.jitter,
+		curve:  o.restartCurve,
+		reset:  o.backoffReset,
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// delay before the next restart, given how long the program ran
+func (b *backoff) next(uptime time.Duration) time.Duration {
+
+	ok := uptime > b.max
+	if b.reset > 0 {
+		ok = uptime >= b.reset
+	}
+
+	if b.tries == 0 || ok {
+		// first crash, or it had been running fine for a while
+		b.tries = 1
+		b.cur = b.min
+	} else {
+		b.tries++
+		b.cur = time.Duration(float64(b.cur) * b.factor)
+		if b.cur > b.max {
+			b.cur = b.max
+		}
+	}
+
+	if b.curve != nil {
+		return b.curve(b.tries)
+	}
+
+	d := b.cur
+	if b.jitter > 0 {
+		d += time.Duration(b.rnd.Float64() * b.jitter * float64(d))
+	}
+	return d
+}
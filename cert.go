@@ -0,0 +1,73 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:34 (EDT)
+// Function: watch a tls certificate
+
+package daemon
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"time"
+)
+
+const certCheckInterval = time.Hour
+
+// CheckCertExpiry(certPath) - how long until the (first) certificate in the pem file expires
+func CheckCertExpiry(certPath string) (time.Duration, error) {
+
+	data, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		var blk *pem.Block
+		blk, data = pem.Decode(data)
+		if blk == nil {
+			return 0, errors.New("no certificate found in " + certPath)
+		}
+		if blk.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(blk.Bytes)
+		if err != nil {
+			return 0, err
+		}
+		return time.Until(cert.NotAfter), nil
+	}
+}
+
+// reload the program when the cert changes, complain when it is about to expire
+func (w *watcher) watchCert() {
+
+	opt := w.opt
+
+	go opt.onFileChange([]string{opt.certPath}, func() {
+		opt.logf(LogLevelInfo, "%s: certificate changed, reloading", opt.certPath)
+		w.checkCert()
+		w.cur.signal(opt.reloadSignal)
+	})
+
+	for {
+		w.checkCert()
+		time.Sleep(certCheckInterval)
+	}
+}
+
+func (w *watcher) checkCert() {
+
+	opt := w.opt
+	left, err := CheckCertExpiry(opt.certPath)
+
+	switch {
+	case err != nil:
+		opt.logf(LogLevelWarn, "%s: cannot check certificate: %v", opt.certPath, err)
+	case left <= 0:
+		opt.logf(LogLevelError, "%s: certificate has expired", opt.certPath)
+	case left < opt.certWarn:
+		opt.logf(LogLevelWarn, "%s: certificate expires in %v", opt.certPath, left.Round(time.Minute))
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:25 (EDT)
+// Function: a cgroup for each run of the program
+
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// create a cgroup (v2) for the program, and move it in
+func (o *opts) newCgroup(pid int) (string, error) {
+
+	dir := filepath.Join(o.cgroupBase, fmt.Sprintf("child-%d", pid))
+
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+
+	procs := filepath.Join(dir, "cgroup.procs")
+	if err := ioutil.WriteFile(procs, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		os.Remove(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// once the program has exited, clean up. kill anything it left behind
+func (o *opts) removeCgroup(dir string) {
+
+	if os.Remove(dir) == nil {
+		return
+	}
+
+	// cgroup.kill is linux 5.14+
+	ioutil.WriteFile(filepath.Join(dir, "cgroup.kill"), []byte("1"), 0644)
+
+	var err error
+	for i := 0; i < 10; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if err = os.Remove(dir); err == nil {
+			return
+		}
+	}
+
+	o.logf(LogLevelWarn, "cannot remove cgroup: %v", err)
+}
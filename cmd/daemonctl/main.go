@@ -0,0 +1,250 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:23 (EDT)
+// Function: control a daemon
+
+// daemonctl [flags] start|stop|status|restart|reload [command args...]
+//
+// the daemon is found with its pidfile. to start it, give the command
+// on the command line, or in the config file. restart reuses the command
+// recorded in the pidfile if none is given. stop sends SIGTERM, which the
+// watcher passes on, the program should exit 0 (see daemon.SigExiter).
+//
+// the config file has lines of: name = value, for pidfile, command, timeout
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jaw0/go-daemon"
+)
+
+type config struct {
+	pidFile string
+	command []string
+	timeout time.Duration
+}
+
+func main() {
+
+	var cf config
+	var confFile string
+
+	flag.StringVar(&confFile, "c", "", "config file")
+	flag.StringVar(&cf.pidFile, "p", "", "pid file")
+	flag.DurationVar(&cf.timeout, "t", 10*time.Second, "how long to wait for start/stop")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] start|stop|status|restart|reload [command args...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if confFile != "" {
+		// flags win
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+		if err := cf.read(confFile, set); err != nil {
+			fail(err)
+		}
+	}
+
+	if args := flag.Args()[1:]; len(args) > 0 {
+		cf.command = args
+	}
+
+	if cf.pidFile == "" {
+		fail(errors.New("no pid file specified"))
+	}
+
+	switch flag.Arg(0) {
+	case "start":
+		fail(cf.start())
+	case "stop":
+		fail(cf.stop())
+	case "restart":
+		fail(cf.restart())
+	case "reload":
+		fail(daemon.SendSignalToDaemon(cf.pidFile, syscall.SIGHUP))
+	case "status":
+		os.Exit(cf.status())
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// complain and exit, if there is a problem
+func fail(err error) {
+
+	if err == nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+
+	var de daemon.DaemonError
+	if errors.As(err, &de) {
+		os.Exit(de.Code())
+	}
+	os.Exit(1)
+}
+
+func (cf *config) read(file string, set map[string]bool) error {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for n := 1; scan.Scan(); n++ {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("%s:%d: expected name = value", file, n)
+		}
+		k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		switch k {
+		case "pidfile":
+			if !set["p"] {
+				cf.pidFile = v
+			}
+		case "command":
+			cf.command = strings.Fields(v)
+		case "timeout":
+			if set["t"] {
+				continue
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %v", file, n, err)
+			}
+			cf.timeout = d
+		default:
+			return fmt.Errorf("%s:%d: unknown parameter %s", file, n, k)
+		}
+	}
+
+	return scan.Err()
+}
+
+// LSB status codes: 0 running, 1 dead with a pidfile, 3 not running
+func (cf *config) status() int {
+
+	pid, err := daemon.ReadPidFile(cf.pidFile)
+
+	switch {
+	case err == nil:
+		fmt.Printf("running, pid %d\n", pid)
+		return 0
+	case errors.Is(err, daemon.ErrNotRunning):
+		fmt.Println("not running")
+		return 3
+	case errors.Is(err, daemon.ErrStalePidFile):
+		fmt.Printf("not running, stale pid file %s\n", cf.pidFile)
+		return 1
+	default:
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 4
+	}
+}
+
+func (cf *config) start() error {
+
+	if err := daemon.CheckPidFile(cf.pidFile); err != nil {
+		return err
+	}
+	if len(cf.command) == 0 {
+		return errors.New("no command specified")
+	}
+
+	// the program puts itself in the background
+	cmd := exec.Command(cf.command[0], cf.command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", cf.command[0], err)
+	}
+
+	return cf.waitFor(func() bool {
+		_, err := daemon.ReadPidFile(cf.pidFile)
+		return err == nil
+	})
+}
+
+func (cf *config) stop() error {
+
+	if err := daemon.SendSignalToDaemon(cf.pidFile, syscall.SIGTERM); err != nil {
+		return err
+	}
+
+	return cf.waitFor(func() bool {
+		_, err := daemon.ReadPidFile(cf.pidFile)
+		return err != nil
+	})
+}
+
+func (cf *config) restart() error {
+
+	if len(cf.command) == 0 {
+		cf.command = pidFileCommand(cf.pidFile)
+	}
+
+	err := cf.stop()
+	if err != nil && !errors.Is(err, daemon.ErrNotRunning) && !errors.Is(err, daemon.ErrStalePidFile) {
+		return err
+	}
+
+	return cf.start()
+}
+
+func (cf *config) waitFor(done func() bool) error {
+
+	deadline := time.Now().Add(cf.timeout)
+
+	for !done() {
+		if time.Now().After(deadline) {
+			return daemon.ErrTimeout
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// the command line, from the last comment in the pidfile
+func pidFileCommand(file string) []string {
+
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	var cmd []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		if strings.HasPrefix(line, "# ") {
+			cmd = strings.Fields(line[2:])
+		}
+	}
+	return cmd
+}
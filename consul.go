@@ -0,0 +1,131 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:31 (EDT)
+// Function: register in consul
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsulServiceDef - the service, as consul's /v1/agent/service/register wants it
+type ConsulServiceDef struct {
+	Name  string            `json:"Name"`
+	ID    string            `json:"ID,omitempty"`
+	Tags  []string          `json:"Tags,omitempty"`
+	Port  int               `json:"Port,omitempty"`
+	Meta  map[string]string `json:"Meta,omitempty"`
+	Check *ConsulCheckDef   `json:"Check,omitempty"`
+}
+
+// ConsulCheckDef - a health check for consul to run
+type ConsulCheckDef struct {
+	HTTP                           string `json:"HTTP,omitempty"`
+	TCP                            string `json:"TCP,omitempty"`
+	TTL                            string `json:"TTL,omitempty"`
+	Interval                       string `json:"Interval,omitempty"`
+	Timeout                        string `json:"Timeout,omitempty"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter,omitempty"`
+}
+
+type consulRegistration struct {
+	addr    string
+	service ConsulServiceDef
+	logf    func(LogLevel, string, ...interface{})
+	queue   serialQueue
+
+	lock       sync.Mutex
+	registered bool
+}
+
+func (r *consulRegistration) event(ev DaemonEvent) {
+
+	switch ev.EventType {
+	case EventStarted:
+		// don't hold up the watcher
+		r.queue.run(func() { r.register(ev.PID) })
+	case EventStopped:
+		r.queue.wait(r.deregister)
+	}
+}
+
+// (re)register, with the program's pid
+func (r *consulRegistration) register(pid int) {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	svc := r.service
+	svc.Meta = map[string]string{"pid": strconv.Itoa(pid)}
+	for k, v := range r.service.Meta {
+		svc.Meta[k] = v
+	}
+
+	if err := r.call("/v1/agent/service/register", svc); err != nil {
+		r.logf(LogLevelWarn, "consul registration: %v", err)
+		return
+	}
+	r.registered = true
+}
+
+func (r *consulRegistration) deregister() {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.registered {
+		return
+	}
+
+	id := r.service.ID
+	if id == "" {
+		id = r.service.Name
+	}
+	if err := r.call("/v1/agent/service/deregister/"+url.PathEscape(id), nil); err != nil {
+		r.logf(LogLevelWarn, "consul deregistration: %v", err)
+	}
+	r.registered = false
+}
+
+func (r *consulRegistration) call(path string, body interface{}) error {
+
+	var buf []byte
+	if body != nil {
+		var err error
+		if buf, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	addr := r.addr
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	req, err := http.NewRequest("PUT", strings.TrimRight(addr, "/")+path, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	c := &http.Client{Timeout: 5 * time.Second}
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:18 (EDT)
+// Function: limit the watcher's cpu use
+
+package daemon
+
+import (
+	"time"
+)
+
+type cpuThrottle struct {
+	frac  float64 // of one cpu
+	last  time.Time
+	used  time.Duration // cpu time, as of last
+	clock Clock
+}
+
+func newCPUThrottle(pct float64, clock Clock) *cpuThrottle {
+	return &cpuThrottle{
+		frac:  pct / 100,
+		last:  clock.Now(),
+		used:  selfCPU(),
+		clock: clock,
+	}
+}
+
+// if we have used too much cpu since last time, pause until we are back under the limit
+func (t *cpuThrottle) wait() time.Duration {
+
+	used := selfCPU()
+	want := time.Duration(float64(used-t.used) / t.frac)
+	took := t.clock.Now().Sub(t.last)
+
+	var pause time.Duration
+	if want > took {
+		pause = want - took
+		t.clock.Sleep(pause)
+	}
+
+	t.used = used
+	t.last = t.clock.Now()
+	return pause
+}
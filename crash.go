@@ -0,0 +1,170 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:59 (EDT)
+// Function: report crashes
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lines of stderr to include in a crash report
+const crashTailLines = 20
+
+type crashReport struct {
+	Time     time.Time         `json:"time"`
+	Program  string            `json:"program"`
+	Pid      int               `json:"pid"`
+	ExitCode int               `json:"exit_code"`
+	Signal   string            `json:"signal,omitempty"`
+	Uptime   float64           `json:"uptime"` // seconds
+	Restarts int               `json:"restarts"`
+	Reason   string            `json:"reason,omitempty"`
+	Message  string            `json:"exit_message,omitempty"`
+	Stderr   []string          `json:"stderr,omitempty"`
+	RSS      int64             `json:"rss_bytes,omitempty"` // last seen, with WithRuntimeMonitor
+	CPU      float64           `json:"cpu_seconds,omitempty"`
+	Audit    []json.RawMessage `json:"audit,omitempty"` // recent audit log entries
+}
+
+func (w *watcher) wantCrashReport() bool {
+	return w.opt.crashURL != "" || w.opt.crashDir != "" || w.opt.hookURL != ""
+}
+
+func (w *watcher) newCrashReport(c *child, st ExitStatus, uptime time.Duration, restarts int, why, msg string) *crashReport {
+
+	r := &crashReport{
+		Time:     w.opt.clock.Now(),
+		Program:  w.prog,
+		Pid:      c.proc.Pid(),
+		ExitCode: st.Code,
+		Uptime:   uptime.Seconds(),
+		Restarts: restarts,
+		Reason:   why,
+		Message:  msg,
+	}
+	if st.Signal != nil {
+		r.Signal = st.Signal.String()
+	}
+	if c.tail != nil {
+		r.Stderr = c.tail.get()
+	}
+	if s, ok := w.stats.get(); ok {
+		r.RSS = s.rss
+		r.CPU = s.cpu.Seconds()
+	}
+	if w.opt.crashDir != "" && w.opt.auditLog != "" {
+		for _, line := range tailFile(w.opt.auditLog, crashTailLines) {
+			// the first may be a fragment
+			if json.Valid([]byte(line)) {
+				r.Audit = append(r.Audit, json.RawMessage(line))
+			}
+		}
+	}
+
+	return r
+}
+
+// WithCrashDump. save the report, and clean up old ones
+func (o *opts) writeCrashDump(r *crashReport) {
+
+	buf, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return
+	}
+
+	name := filepath.Join(o.crashDir, fmt.Sprintf("crash-%s-%d.json", r.Time.UTC().Format("20060102T150405.000"), r.Pid))
+	if err := ioutil.WriteFile(name, append(buf, '\n'), 0644); err != nil {
+		o.logf(LogLevelError, "crash dump: %v", err)
+		return
+	}
+
+	if o.maxCrashDumps <= 0 {
+		return
+	}
+
+	// the names sort by time
+	old, _ := filepath.Glob(filepath.Join(o.crashDir, "crash-*.json"))
+	sort.Strings(old)
+	for len(old) > o.maxCrashDumps {
+		os.Remove(old[0])
+		old = old[1:]
+	}
+}
+
+// the last few lines of a file, without reading all of it
+func tailFile(path string, n int) []string {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	const chunk = 64 * 1024
+	if fi, err := f.Stat(); err == nil && fi.Size() > chunk {
+		f.Seek(-chunk, io.SeekEnd)
+	}
+	buf, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// send the report, without holding up the restart
+func (o *opts) postCrashReport(r *crashReport) {
+	o.postReport("crash report", o.crashURL, o.crashHeaders, 30*time.Second, r)
+}
+
+// WithRestartHook
+func (o *opts) postRestartHook(r *crashReport) {
+	o.postReport("restart hook", o.hookURL, nil, o.hookTimeout, r)
+}
+
+func (o *opts) postReport(what, url string, headers map[string]string, timeout time.Duration, r *crashReport) {
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(buf))
+		if err != nil {
+			o.logf(LogLevelError, "%s: %v", what, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		c := &http.Client{Timeout: timeout}
+		res, err := c.Do(req)
+		if err != nil {
+			o.logf(LogLevelError, "%s: %v", what, err)
+			return
+		}
+		res.Body.Close()
+		if res.StatusCode >= 300 {
+			o.logf(LogLevelError, "%s: %s", what, res.Status)
+		}
+	}()
+}
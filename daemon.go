@@ -7,12 +7,21 @@
 package daemon
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -22,49 +31,358 @@ const (
 
 const ENVVAR = "_dmode"
 
+// WithExecAsUser, set once we have switched, so we don't set up again
+const execUserVar = "_dexecuser"
+
+// the pipe the program uses to tell the watcher it cannot be set up
+const setupVar = "_dsetup"
+
+// WithExclusivePidFileWrite, the initial process holds the pidfile for the watcher
+const pidClaimVar = "_dpidfile"
+
 type opts struct {
-	keepStderr   bool
-	justOne      bool
-	testDelay    bool
-	restartDelay time.Duration
-	pidFile      string
+	keepStderr     bool
+	justOne        bool
+	testDelay      bool
+	restartDelay   time.Duration
+	restartMax     time.Duration
+	restartFactor  float64
+	jitter         float64
+	pidFile        string
+	tcpCheck       string
+	tcpTimeout     time.Duration
+	httpCheck      string
+	httpTimeout    time.Duration
+	httpInterval   time.Duration
+	healthFails    int
+	readyProbe     func() bool
+	readyTimeout   time.Duration
+	liveProbe      func() bool
+	liveInterval   time.Duration
+	liveFails      int
+	notify         func(DaemonEvent)
+	eventChan      chan<- DaemonEvent
+	watchFiles     []string
+	reloadSignal   os.Signal
+	rateLimit      float64
+	auditLog       string
+	spans          *spanTracker
+	clock          Clock
+	stderrPatterns []stderrPattern
+	exitMsgFile    string
+	childPidFile   string
+	pidValidator   func(string) error
+	logLevel       LogLevel
+	sigNotify      chan<- os.Signal
+	envFilters     []func(string, string) bool
+	deferPidFile   bool
+	restartCurve   func(int) time.Duration
+	minUptime      time.Duration
+	stdoutHandlers []func(string)
+	stderrHandlers []func(string)
+	execPath       string
+	args           []string
+	respawnLimit   int
+	foreground     bool
+	stdoutFile     string
+	stderrFile     string
+	tee            bool
+	crashURL       string
+	fdLimit        uint64
+	threadLimit    uint64
+	afterExit      func()
+	detachTTY      bool
+	mountNS        bool
+	bindMounts     []bindMount
+	netNS          string
+	pidHeader      []string
+	sigCooldown    map[os.Signal]time.Duration
+	sigPersist     map[os.Signal]signalPersist
+	pidSync        bool
+	pidExcl        bool
+	pidClaim       int // the initial process's pid, in the pidfile until we replace it
+	cpuLimit       float64
+	updateCheck    bool
+	autoRemovePid  bool
+	startSignal    os.Signal
+	secrets        []secret
+	secretsSent    sync.WaitGroup
+	cgroupBase     string
+	pidExpiry      time.Duration
+	subreaper      bool
+	forkTimeout    time.Duration
+	prep           [][]string
+	stdinFile      string
+	stdinPipe      *os.File // read end
+	stdinWriter    *os.File
+	signalFiles    []signalFile
+	configFiles    []string
+	sdWatchdog     bool
+	etcd           *etcdRegistration
+	consul         *consulRegistration
+	sockets        []string
+	sockFiles      []*os.File
+	dnsCheck       string
+	dnsTimeout     time.Duration
+	certPath       string
+	certWarn       time.Duration
+	monInterval    time.Duration
+	metricsAddr    string
+	promLabels     string // WithPrometheusLabels, formatted
+	cleanup        func(ExitReason)
+	backoffReset   time.Duration
+	launchd        bool
+	envCapture     string
+	waitSig        os.Signal
+	parentPid      int32 // WithSignalWait, who to tell
+	fdCloexec      bool
+	pidDirs        []string
+	crashDir       string
+	maxCrashDumps  int
+	hookURL        string
+	hookTimeout    time.Duration
+	zombieReaper   bool
+	stateFile      string
+	notifyParent   bool
+	maxStartup     time.Duration
+	redisLock      *redisLock
+	pidOwner       bool
+	pidUid         int
+	pidGid         int
+	checkInterval  time.Duration // for all health checks
+	checkFails     int
+	execUser       string
+	portVars       []string
+	readOnlyFS     bool
+	writable       []string // WithReadOnlyFS
+	panicRestart   bool
+	exitSigs       []os.Signal
+	restartSigs    []os.Signal
+	proxyPidFile   string
+	proxySigs      []os.Signal
+	strategy       RestartStrategy
+	afterCrash     []string
+	tempDir        string
+	crashHeaders   map[string]string
+	optErr         error // a problem with the options
 }
 type optFunc func(*opts)
 
-// daemon.Ize(WithOpts...) - run program as a daemon
-func Ize(optfn ...optFunc) {
+type signalFile struct {
+	sig  os.Signal
+	path string
+}
+
+type signalPersist struct {
+	interval time.Duration
+	max      int
+}
+
+type bindMount struct {
+	src string
+	dst string
+}
+
+// Option is the type of the WithX options
+type Option = optFunc
+
+func newOpts(optfn []optFunc) *opts {
 
 	opt := &opts{
-		restartDelay: 5 * time.Second,
+		restartDelay:  5 * time.Second,
+		restartMax:    5 * time.Second,
+		restartFactor: 1,
+		healthFails:   3,
+		reloadSignal:  syscall.SIGHUP,
+		certWarn:      7 * 24 * time.Hour,
+		hookTimeout:   10 * time.Second,
+		clock:         realClock{},
+		logLevel:      LogLevelInfo,
 	}
 	for _, fn := range optfn {
 		fn(opt)
 	}
+	opt.selectPidDir()
+	if cs, ok := opt.strategy.(interface{ useClock(Clock) }); ok {
+		cs.useClock(opt.clock)
+	}
+	if opt.pidFile != "" && opt.pidExcl && opt.deferPidFile && opt.waitSig == nil && !opt.justOne {
+		// the initial process waits, so it can report a conflict
+		opt.waitSig = sigReady
+	}
+	return opt
+}
+
+// LazyIze(WithOpts...) - returns a function that runs Ize, for when the program wants to parse args, etc first
+// the watcher and main program run main again, so they must get to it the same way. only the first call does anything
+func LazyIze(optfn ...optFunc) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { Ize(optfn...) })
+	}
+}
+
+// daemon.Ize(WithOpts...) - run program as a daemon
+func Ize(optfn ...optFunc) {
+
+	opt := newOpts(optfn)
+	exitMessageFile = opt.exitMsgFile
+
+	if opt.optErr != nil {
+		opt.fatal(wrapErr(ErrStartFailed, opt.optErr))
+	}
+
+	exitSignals, restartSignals = opt.exitSigs, opt.restartSigs
 
 	mode := os.Getenv(ENVVAR)
 	prog, err := os.Executable()
 
 	if err != nil {
-		fmt.Printf("cannot daemonize: %v", err)
-		os.Exit(2)
+		opt.fatal(wrapErr(ErrStartFailed, err))
+	}
+
+	// the program the watcher runs
+	mainProg := prog
+	if opt.execPath != "" {
+		mainProg = opt.execPath
 	}
 
 	if mode == "" {
 		// initial execution
+		if opt.pidFile != "" && opt.pidValidator != nil {
+			// find problems while we can still complain about them
+			if err := opt.pidValidator(opt.pidFile); err != nil {
+				opt.fatal(wrapErr(ErrStartFailed, err))
+			}
+		}
+		if opt.fdCloexec {
+			closeOnExec()
+		}
+		for _, cmd := range opt.prep {
+			if err := runPrep(cmd); err != nil {
+				opt.fatal(wrapErr(ErrStartFailed, err))
+			}
+		}
+		if opt.envCapture != "" {
+			if err := opt.captureEnv(); err != nil {
+				opt.logf(LogLevelWarn, "cannot save environment: %v", err)
+			}
+		}
+		if opt.tempDir != "" {
+			if err := os.MkdirAll(opt.tempDir, 0755); err != nil {
+				opt.fatal(wrapErr(ErrStartFailed, err))
+			}
+		}
+		if err := opt.setRlimits(); err != nil {
+			opt.fatal(wrapErr(ErrStartFailed, err))
+		}
+		if err := opt.checkNamespaces(); err != nil {
+			opt.fatal(wrapErr(ErrStartFailed, err))
+		}
+		if err := opt.checkPidOwner(); err != nil {
+			opt.fatal(wrapErr(ErrStartFailed, err))
+		}
+	}
+
+	if opt.launchd && underLaunchd(mode) {
+		// launchd expects us to stay put
+		opt.foreground = true
+	}
+
+	if mode == "" && !opt.foreground && !canDetach {
+		opt.logf(LogLevelWarn, "running in the background is not supported here, running in the foreground")
+		opt.foreground = true
+	}
+
+	if mode == "" && opt.foreground {
+		// runit, s6, et al. want us to stay put
+		if opt.justOne {
+			// we are the main program
+			if err := opt.checkInProcess(); err != nil {
+				opt.fatal(wrapErr(ErrStartFailed, err))
+			}
+			opt.setupProgram()
+			return
+		}
+		mode = "1"
+	}
+
+	if mode == "" {
 		// switch to the background
 		if opt.justOne {
+			if opt.detachTTY {
+				// there would be nothing to lead its session
+				opt.fatal(wrapErr(ErrStartFailed, errors.New("WithDetachTTY cannot be used with WithNoRestart")))
+			}
 			// only run the main program as a daemon
 			os.Setenv(ENVVAR, "2")
 		} else {
 			// run the main program + watcher as daemons
 			os.Setenv(ENVVAR, "1")
+			if opt.pidFile != "" && opt.pidExcl && !opt.deferPidFile {
+				opt.claimPidFile()
+			}
 		}
+		waitc := opt.prepareSignalWait()
 		dn, _ := os.OpenFile(os.DevNull, os.O_RDWR, 0666)
-		pa := &os.ProcAttr{Files: []*os.File{dn, dn, os.Stderr}}
+		var p Process
+		pa := &os.ProcAttr{Files: []*os.File{dn, dn, os.Stderr}, Env: opt.environ()}
+		if opt.justOne {
+			pa.Sys = opt.sysProcAttr()
+		}
 		if !opt.keepStderr {
 			pa.Files[2] = dn
 		}
-		os.StartProcess(prog, os.Args, pa)
+		if opt.justOne {
+			// no watcher to do it
+			if opt.stdinPipe != nil {
+				pa.Files[0] = opt.stdinPipe
+			}
+			if opt.stdinFile != "" {
+				f, err := os.Open(opt.stdinFile)
+				if err != nil {
+					opt.fatal(wrapErr(ErrStartFailed, err))
+				}
+				pa.Files[0] = f
+			}
+			if opt.stdoutFile != "" {
+				if f, err := openOutputFile(opt.stdoutFile); err == nil {
+					pa.Files[1] = f
+				}
+			}
+			if opt.stderrFile != "" {
+				if f, err := openOutputFile(opt.stderrFile); err == nil {
+					pa.Files[2] = f
+				}
+			}
+			if err := opt.openSockets(); err != nil {
+				opt.fatal(wrapErr(ErrStartFailed, err))
+			}
+			opt.addSockets(pa)
+			if err := opt.addPorts(pa); err != nil {
+				opt.fatal(wrapErr(ErrStartFailed, err))
+			}
+			if _, err := opt.addSecrets(pa); err != nil {
+				opt.fatal(wrapErr(ErrStartFailed, err))
+			}
+			p, err = opt.startWithTimeout(func() (Process, error) {
+				return opt.startInNamespace(func() (Process, error) {
+					return startProcess(mainProg, opt.mainArgs(), pa)
+				})
+			})
+		} else {
+			p, err = opt.startWithTimeout(func() (Process, error) {
+				return startProcess(prog, os.Args, pa)
+			})
+		}
+		if err != nil {
+			opt.fatal(wrapErr(ErrStartFailed, err))
+		}
+		if waitc != nil {
+			opt.waitForSignal(p, waitc)
+		}
+		// before we exit
+		opt.secretsSent.Wait()
 		if opt.testDelay {
 			// 'go test' will delete the executable file, take a pause
 			time.Sleep(1 * time.Second)
@@ -72,100 +390,438 @@ func Ize(optfn ...optFunc) {
 		os.Exit(0)
 	}
 
-	syscall.Setsid()
+	// WithDetachTTY: the program stays in the watcher's session, without leading it,
+	// so it can never acquire a controlling terminal
+	if !opt.foreground && !(mode == "2" && opt.detachTTY) {
+		setsid()
+	}
 
 	if mode == "2" {
 		// run and be the main program
+		opt.setupProgram()
 		return
 	}
 
-	var sigchan = make(chan os.Signal, 5)
-	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	opt.loadParent()
+	opt.loadPidClaim()
 
-	if opt.pidFile != "" {
-		opt.savePidFile()
+	w := newWatcher(opt, mainProg)
+	signal.Notify(w.sigchan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	if opt.startSignal != nil {
+		signal.Notify(w.sigchan, opt.startSignal)
 	}
+	if opt.notifyParent || opt.maxStartup > 0 {
+		signal.Notify(w.readyc, sigReady)
+	}
+	os.Exit(w.run())
+}
 
-	// watch + restart
-	for {
-		os.Setenv(ENVVAR, "2")
-		dn, _ := os.OpenFile(os.DevNull, os.O_RDWR, 0666)
-		pa := &os.ProcAttr{Files: []*os.File{dn, dn, os.Stderr}}
-		if !opt.keepStderr {
-			pa.Files[2] = dn
-		}
-		p, err := os.StartProcess(prog, os.Args, pa)
-		if err != nil {
-			fmt.Printf("cannot start %s: %v", prog, err)
-			os.Exit(2)
-		}
-
-		stop := make(chan struct{})
-		var wg sync.WaitGroup
-		wg.Add(1)
+// in the main program, before returning to it
+func (o *opts) setupProgram() {
 
-		go func() {
-			defer wg.Done()
-			select {
-			case <-stop:
-				return
-			case n := <-sigchan:
-				// pass the signal on through to the running program
-				p.Signal(n)
-			}
-		}()
+	loadRespawnCount()
+	if o.stdinPipe != nil {
+		// ours is on stdin, this one is unused
+		o.stdinPipe.Close()
+		o.stdinWriter.Close()
+	}
 
-		st, _ := p.Wait()
-		if !st.Exited() {
-			continue
+	if os.Getenv(execUserVar) != "" {
+		// started over as the user, the namespaces are already set up, and we cannot anymore
+		os.Unsetenv(execUserVar)
+	} else {
+		if err := o.enterNamespaces(); err != nil {
+			o.setupFailed(err)
 		}
-		if st.Success() {
-			// done
-			if opt.pidFile != "" {
-				opt.removePidFile()
+		if o.execUser != "" {
+			if err := o.execAsUser(); err != nil {
+				o.setupFailed(err)
 			}
-			os.Exit(0)
 		}
+	}
+	if f := setupPipe(); f != nil {
+		// all set, nothing to report
+		f.Close()
+	}
+	os.Unsetenv(setupVar)
+	panicRestart = o.panicRestart
+	o.startProxy()
+	o.setNotifyReady()
+}
+
+// it will only fail the same way again, tell the watcher not to restart it
+func (o *opts) setupFailed(err error) {
+
+	err = wrapErr(ErrStartFailed, err)
+	if f := setupPipe(); f != nil {
+		f.WriteString(err.Error())
+		f.Close()
+	}
+	o.fatal(err)
+}
+
+// in the main program, from the watcher, if we have setup that can fail
+func setupPipe() *os.File {
+
+	fd, err := strconv.Atoi(os.Getenv(setupVar))
+	if err != nil {
+		return nil
+	}
+	return os.NewFile(uintptr(fd), setupVar)
+}
+
+// WithMountNamespace, WithExecAsUser. can setting up the main program fail?
+func (o *opts) needsSetup() bool {
+	return o.mountNS || o.execUser != ""
+}
+
+// WithNoRestart in the foreground, we become the main program, without starting another
+// these need the main program to be started for them
+func (o *opts) checkInProcess() error {
+
+	var need []string
+	if o.mountNS {
+		need = append(need, "WithMountNamespace")
+	}
+	if o.netNS != "" {
+		need = append(need, "WithNetworkNamespace")
+	}
+	if len(o.secrets) > 0 {
+		need = append(need, "WithChildEnvSecret")
+	}
+	if len(o.sockets) > 0 {
+		need = append(need, "WithManagedSocket")
+	}
+	if len(o.portVars) > 0 {
+		need = append(need, "WithNegotiatedPort")
+	}
+	if len(need) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s cannot be used with WithNoRestart in the foreground", strings.Join(need, ", "))
+}
 
-		close(stop)
-		wg.Wait()
-		time.Sleep(opt.restartDelay)
+// the command line for the main program
+func (o *opts) mainArgs() []string {
+	if o.args == nil {
+		return os.Args
 	}
+	return append([]string{os.Args[0]}, o.args...)
 }
 
 func (o *opts) savePidFile() error {
+	return o.createPidFile(os.Getpid())
+}
+
+// write the pidfile, with WithExclusivePidFileWrite, only if no one else has
+func (o *opts) createPidFile(pid int) error {
+
+	if !o.pidExcl {
+		return o.writePidFile(o.pidFile, pid, false)
+	}
 
-	f, err := os.Create(o.pidFile)
+	// only one of us at a time decides whether it is stale
+	unlock, err := lockFile(o.pidLockFile())
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	err = o.writePidFile(o.pidFile, pid, true)
+	if !os.IsExist(err) {
+		return err
+	}
+
+	other, rerr := ReadPidFile(o.pidFile)
+	if o.pidClaim != 0 && other == o.pidClaim {
+		// held for us
+		return o.writePidFile(o.pidFile, pid, false)
+	}
+	if rerr == nil && o.pidFileExpired() {
+		// probably a different process, from before a reboot
+		o.logf(LogLevelWarn, "%s is older than %v, ignoring pid %d", o.pidFile, o.pidExpiry, other)
+		rerr = ErrStalePidFile
+	}
+	if rerr == nil {
+		return wrapErrf(ErrAlreadyRunning, "pid %d (%s)", other, o.pidFile)
+	}
+	if !errors.Is(rerr, ErrStalePidFile) {
+		return err
+	}
+
+	// left behind by a crash. anyone else wanting it is waiting for the lock
+	return o.writePidFile(o.pidFile, pid, false)
+}
+
+// with WithExclusivePidFileWrite, the initial process takes the pidfile before it leaves,
+// so it can still complain if someone else has it. the watcher replaces it
+func (o *opts) claimPidFile() {
+	if err := o.createPidFile(os.Getpid()); err != nil {
+		o.fatal(wrapErr(ErrStartFailed, err))
+	}
+	os.Setenv(pidClaimVar, strconv.Itoa(os.Getpid()))
+}
+
+// in the watcher, was it held for us?
+func (o *opts) loadPidClaim() {
+	o.pidClaim, _ = strconv.Atoi(os.Getenv(pidClaimVar))
+	os.Unsetenv(pidClaimVar)
+}
+
+// with WithPidFileExpiry, is the pidfile too old to believe?
+func (o *opts) pidFileExpired() bool {
+
+	if o.pidExpiry <= 0 {
+		return false
+	}
+	st, err := os.Stat(o.pidFile)
+	if err != nil {
+		return false
+	}
+	return time.Since(st.ModTime()) > o.pidExpiry
+}
+
+// WithPidFileOwnership. only root can give files away
+// others can only pick one of their own groups
+func (o *opts) checkPidOwner() error {
+
+	if !o.pidOwner || os.Geteuid() == 0 {
+		return nil
+	}
+	if o.pidUid != -1 && o.pidUid != os.Geteuid() {
+		return fmt.Errorf("cannot chown pid file to uid %d, not root", o.pidUid)
+	}
+	if o.pidGid == -1 || o.pidGid == os.Getegid() {
+		return nil
+	}
+	groups, _ := os.Getgroups()
+	for _, g := range groups {
+		if g == o.pidGid {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot chown pid file to gid %d, not a member", o.pidGid)
+}
+
+func (o *opts) writePidFile(file string, pid int, excl bool) error {
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%d\n", pid)
 
-	fmt.Fprintf(f, "%d\n", os.Getpid())
+	for _, line := range o.pidHeader {
+		fmt.Fprintf(&buf, "# %s\n", line)
+	}
 
 	prog, err := os.Executable()
 	if err == nil {
-		f.WriteString(fmt.Sprintf("# %s", prog))
+		buf.WriteString(fmt.Sprintf("# %s", prog))
 		for _, arg := range os.Args[1:] {
-			f.WriteString(" ")
-			f.WriteString(arg)
+			buf.WriteString(" ")
+			buf.WriteString(arg)
 		}
-		f.WriteString("\n")
+		buf.WriteString("\n")
 	}
 
-	f.Close()
-	return nil
+	chown := func(f *os.File) error {
+		if o.pidOwner {
+			if err := f.Chown(o.pidUid, o.pidGid); err != nil {
+				o.logf(LogLevelWarn, "%s: %v", file, err)
+			}
+		}
+		return nil
+	}
+
+	if o.pidSync {
+		return writeFileAtomic(file, []byte(buf.String()), 0644, excl, chown)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if excl {
+		flags |= os.O_EXCL
+	}
+
+	f, err := os.OpenFile(file, flags, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	chown(f)
+	_, err = f.WriteString(buf.String())
+	return err
 }
 
 func (o *opts) removePidFile() {
 	os.Remove(o.pidFile)
 }
 
+// WithExclusivePidFileWrite, held while deciding who gets the pidfile
+func (o *opts) pidLockFile() string {
+	return o.pidFile + ".lock"
+}
+
+// with WithExclusivePidFileWrite, don't remove someone else's
+func (o *opts) removePidFileOf(pid int) {
+
+	if !o.pidExcl {
+		o.removePidFile()
+		return
+	}
+
+	unlock, err := lockFile(o.pidLockFile())
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	if p, _ := ReadPidFile(o.pidFile); p != pid {
+		return
+	}
+	o.removePidFile()
+	// anyone waiting for the lock will see it is gone, and take a new one
+	os.Remove(o.pidLockFile())
+}
+
+// ReadPidFile(filename) - the pid of the running daemon
+// ErrNotRunning if there is no pidfile, ErrStalePidFile (and the pid) if the process is gone
+func ReadPidFile(file string) (int, error) {
+
+	buf, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return 0, wrapErr(ErrNotRunning, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	line := strings.SplitN(string(buf), "\n", 2)[0]
+	pid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || pid <= 0 {
+		return 0, wrapErrf(ErrStalePidFile, "%s: invalid pid %q", file, line)
+	}
+
+	if err := pidAlive(pid); err != nil {
+		return pid, wrapErrf(ErrStalePidFile, "%s: pid %d: %v", file, pid, err)
+	}
+
+	return pid, nil
+}
+
+// CheckPidFile(filename) - ErrAlreadyRunning if the pidfile belongs to a running process
+// for use with WithPidFileValidator
+func CheckPidFile(file string) error {
+
+	pid, err := ReadPidFile(file)
+	if errors.Is(err, ErrNotRunning) || errors.Is(err, ErrStalePidFile) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return wrapErrf(ErrAlreadyRunning, "pid %d (%s)", pid, file)
+}
+
+// SendSignalToDaemon(filename, sig) - signal the daemon in the pidfile. the watcher passes it on to the program
+func SendSignalToDaemon(file string, sig os.Signal) error {
+
+	pid, err := ReadPidFile(file)
+	if err != nil {
+		return err
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(sig)
+}
+
+// WithProxySignals. in the main program, pass the signals on to the process in the pid file
+func (o *opts) startProxy() {
+
+	if o.proxyPidFile == "" || len(o.proxySigs) == 0 {
+		return
+	}
+
+	sigchan := make(chan os.Signal, 5)
+	signal.Notify(sigchan, o.proxySigs...)
+
+	go func() {
+		for n := range sigchan {
+			if err := SendSignalToDaemon(o.proxyPidFile, n); err != nil {
+				o.logf(LogLevelWarn, "cannot pass on %v: %v", n, err)
+			}
+		}
+	}()
+}
+
+// WithExecAfterCrash. run the command, and wait for it, before restarting
+func (o *opts) runAfterCrash(pid int, st ExitStatus) {
+
+	cmd := exec.Command(o.afterCrash[0], o.afterCrash[1:]...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"DAEMON_PID="+strconv.Itoa(pid),
+		"DAEMON_EXIT_CODE="+strconv.Itoa(st.Code),
+	)
+	if st.Signal != nil {
+		cmd.Env = append(cmd.Env, "DAEMON_SIGNAL="+st.Signal.String())
+	}
+
+	if err := cmd.Run(); err != nil {
+		o.logf(LogLevelWarn, "%s: %v", o.afterCrash[0], err)
+	}
+}
+
+// run a WithExecPrep command, while we still have a terminal
+func runPrep(args []string) error {
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %v", args[0], err)
+	}
+	return nil
+}
+
+// complain and exit
+func (o *opts) fatal(err error) {
+	o.logf(LogLevelError, "%v", err)
+	os.Exit(errorCode(err))
+}
+
+// RequestRestart() - exit, asking the watcher to restart us. does not return
+func RequestRestart() {
+	os.Exit(ExitRestart)
+}
+
+// set by Ize, with WithExitOnSignal, WithRestartOnSignal
+var exitSignals, restartSignals []os.Signal
+
 func SigExiter() {
 	var sigchan = make(chan os.Signal, 5)
 	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	// careful, no signals means all signals
+	if len(exitSignals) > 0 {
+		signal.Notify(sigchan, exitSignals...)
+	}
+	if len(restartSignals) > 0 {
+		signal.Notify(sigchan, restartSignals...)
+	}
 
 	select {
 	case n := <-sigchan:
+		if hasSignal(exitSignals, n) {
+			os.Exit(0)
+		}
+		if hasSignal(restartSignals, n) {
+			os.Exit(ExitRestart)
+		}
 		switch n {
 		case syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
 			os.Exit(0)
@@ -177,6 +833,15 @@ func SigExiter() {
 	}
 }
 
+func hasSignal(sigs []os.Signal, sig os.Signal) bool {
+	for _, s := range sigs {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
+
 // WithPidFile(filename) - specify a pidfile
 func WithPidFile(file string) func(*opts) {
 	return func(opt *opts) {
@@ -184,24 +849,876 @@ func WithPidFile(file string) func(*opts) {
 	}
 }
 
-// WithNoRestart() - don't run a 2nd daemon to watch + restart
-func WithNoRestart() func(*opts) {
+// WithPidFileHeader(lines...) - add comment lines (hostname, version, ...) to the pidfiles, after the pid
+func WithPidFileHeader(lines ...string) func(*opts) {
 	return func(opt *opts) {
-		opt.justOne = true
+		opt.pidHeader = append(opt.pidHeader, lines...)
 	}
 }
 
-// WithRestartDelay(time.Duration) - delay restart when running WithStayAlive
-func WithRestartDelay(d time.Duration) func(*opts) {
+// WithPidFileOwnership(uid, gid) - chown the pid file, eg. so a monitoring user can read it. -1 leaves it unchanged
+func WithPidFileOwnership(uid, gid int) func(*opts) {
 	return func(opt *opts) {
-		opt.restartDelay = d
+		opt.pidOwner = true
+		opt.pidUid = uid
+		opt.pidGid = gid
 	}
 }
 
-// WithStderr() - keep stderr open for output
-func WithStderr() func(*opts) {
+// WithPidFileSyncOnWrite() - write pidfiles atomically, and sync them to disk
+func WithPidFileSyncOnWrite() func(*opts) {
 	return func(opt *opts) {
-		opt.keepStderr = true
+		opt.pidSync = true
+	}
+}
+
+// WithExclusivePidFileWrite() - only write the pidfile if it does not already exist
+// if it belongs to a running process, give up with ErrAlreadyRunning. the initial process
+// waits to find out. pidfile.lock is held while taking over stale ones, and removed with the pidfile
+func WithExclusivePidFileWrite() func(*opts) {
+	return func(opt *opts) {
+		opt.pidExcl = true
+	}
+}
+
+// WithAutoRemovePidFile() - remove the pidfile however the watcher exits, not only when the program finishes
+func WithAutoRemovePidFile() func(*opts) {
+	return func(opt *opts) {
+		opt.autoRemovePid = true
+	}
+}
+
+// WithPidFileExpiry(dur) - with WithExclusivePidFileWrite, a pidfile older than dur is stale, even if the pid is running
+func WithPidFileExpiry(d time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.pidExpiry = d
+	}
+}
+
+// WithChildPidFile(filename) - specify a pidfile for the main program (rewritten on each restart)
+func WithChildPidFile(file string) func(*opts) {
+	return func(opt *opts) {
+		opt.childPidFile = file
+	}
+}
+
+// WithPidFiles(watcher, child) - specify pidfiles for both the watcher and the main program
+func WithPidFiles(watcher, child string) func(*opts) {
+	return func(opt *opts) {
+		opt.pidFile = watcher
+		opt.childPidFile = child
+	}
+}
+
+// WithDeferredPidFile() - write the main program's pid to the pidfile, once it passes the startup checks
+// or with WithNotifyParent, once it calls NotifyReady
+func WithDeferredPidFile() func(*opts) {
+	return func(opt *opts) {
+		opt.deferPidFile = true
+	}
+}
+
+// WithPidFileValidator(fn) - check the pidfile can be written before switching to the background
+func WithPidFileValidator(fn func(path string) error) func(*opts) {
+	return func(opt *opts) {
+		opt.pidValidator = fn
+	}
+}
+
+// WithSupervisorCompatMode() - don't switch to the background, for running under runit, s6, daemontools, etc.
+// the watcher stays in the foreground, and the main program's stdout + stderr are connected to ours
+func WithSupervisorCompatMode() func(*opts) {
+	return func(opt *opts) {
+		opt.foreground = true
+	}
+}
+
+// WithLaunchdMode() - when started by launchd, don't switch to the background, as WithSupervisorCompatMode
+// otherwise, run as usual
+func WithLaunchdMode() func(*opts) {
+	return func(opt *opts) {
+		opt.launchd = true
+	}
+}
+
+// WithNoRestart() - don't run a 2nd daemon to watch + restart
+func WithNoRestart() func(*opts) {
+	return func(opt *opts) {
+		opt.justOne = true
+	}
+}
+
+// WithStayAlive() - run a 2nd daemon to watch + restart (the default)
+func WithStayAlive() func(*opts) {
+	return func(opt *opts) {
+		opt.justOne = false
+	}
+}
+
+// IsStayAlive(WithOpts...) - will these options run the watcher?
+func IsStayAlive(optfn ...optFunc) bool {
+	return !newOpts(optfn).justOne
+}
+
+// WithRestartDelay(time.Duration) - delay restart when running WithStayAlive
+//
+// Deprecated: use WithRestartBackoff(d, d, 1.0)
+func WithRestartDelay(d time.Duration) func(*opts) {
+	return WithRestartBackoff(d, d, 1.0)
+}
+
+// WithRestartBackoff(min, max, factor) - delay restart by min, growing by factor after each crash, up to max
+// the delay resets to min once the program has run for longer than max, see WithRestartBackoffReset
+func WithRestartBackoff(min, max time.Duration, factor float64) func(*opts) {
+	return func(opt *opts) {
+		opt.restartDelay = min
+		opt.restartMax = max
+		opt.restartFactor = factor
+	}
+}
+
+// WithRestartStrategy(strategy) - decide whether, and when, to restart the program after it fails
+// takes the place of WithRestartBackoff, WithRestartDelayCurve, etc. see FixedDelayStrategy, ExponentialBackoffStrategy, CircuitBreakerStrategy
+func WithRestartStrategy(strategy RestartStrategy) func(*opts) {
+	return func(opt *opts) {
+		opt.strategy = strategy
+	}
+}
+
+// WithRestartBackoffReset(minRuntime) - only reset the restart backoff once the program has run for at least minRuntime
+// the default is the backoff max
+func WithRestartBackoffReset(minRuntime time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.backoffReset = minRuntime
+	}
+}
+
+// WithRestartDelayCurve(fn) - fn(attempt) gives the delay before each restart, attempt counts from 1
+// and starts over once the program has run for longer than the backoff max
+func WithRestartDelayCurve(fn func(attempt int) time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.restartCurve = fn
+	}
+}
+
+// WithMinUptime(d) - exiting sooner than d after starting counts as a crash, running longer resets the crash count
+func WithMinUptime(d time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.minUptime = d
+	}
+}
+
+// WithRespawnLimit(n) - stop after restarting the main program n times
+func WithRespawnLimit(n int) func(*opts) {
+	return func(opt *opts) {
+		opt.respawnLimit = n
+	}
+}
+
+// WithJitter(fraction) - add up to fraction * delay of randomness to the restart delay
+func WithJitter(fraction float64) func(*opts) {
+	return func(opt *opts) {
+		opt.jitter = fraction
+	}
+}
+
+// WithStdinFile(filename) - the program reads stdin from the file, rather than /dev/null
+func WithStdinFile(file string) func(*opts) {
+	return func(opt *opts) {
+		opt.stdinFile = file
+	}
+}
+
+// WithStdinPipe() - the program's stdin is a pipe, write to it (in the watcher) with the returned WriteCloser
+// closing it sends EOF. the same pipe is used for each restart
+func WithStdinPipe() (io.WriteCloser, func(*opts)) {
+	pr, pw, err := os.Pipe()
+	return pw, func(opt *opts) {
+		if err != nil {
+			opt.optErr = err
+			return
+		}
+		opt.stdinPipe = pr
+		opt.stdinWriter = pw
+	}
+}
+
+// WithStdoutFile(filename) - append the main program's stdout to the file
+func WithStdoutFile(file string) func(*opts) {
+	return func(opt *opts) {
+		opt.stdoutFile = file
+	}
+}
+
+// WithStderrFile(filename) - append the main program's stderr to the file
+func WithStderrFile(file string) func(*opts) {
+	return func(opt *opts) {
+		opt.stderrFile = file
+	}
+}
+
+// WithTeeOutput() - with WithStdoutFile or WithStderrFile, also send the output where it would otherwise go
+func WithTeeOutput() func(*opts) {
+	return func(opt *opts) {
+		opt.tee = true
+	}
+}
+
+// WithStderr() - keep stderr open for output
+func WithStderr() func(*opts) {
+	return func(opt *opts) {
+		opt.keepStderr = true
+	}
+}
+
+// WithTCPPortCheck(addr, timeout) - after starting, verify that the program is listening on addr
+func WithTCPPortCheck(addr string, timeout time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.tcpCheck = addr
+		opt.tcpTimeout = timeout
+	}
+}
+
+// WithHTTPHealthCheck(url, timeout, interval) - periodically GET url, restart the program if it keeps failing
+// a timeout of 0 means 5 seconds, an interval of 0 means every 10 seconds
+func WithHTTPHealthCheck(url string, timeout time.Duration, interval time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.httpCheck = url
+		opt.httpTimeout = timeout
+		opt.httpInterval = interval
+	}
+}
+
+// WithHealthCheckFailThreshold(n) - restart after n consecutive failed health checks (default 3)
+// applies to WithHTTPHealthCheck and WithLivenessProbe, overriding their own thresholds. n must be at least 1
+func WithHealthCheckFailThreshold(n int) func(*opts) {
+	return func(opt *opts) {
+		if n < 1 {
+			opt.optErr = fmt.Errorf("health check fail threshold must be at least 1, not %d", n)
+			return
+		}
+		opt.checkFails = n
+	}
+}
+
+// WithHealthCheckInterval(d) - run the health checks every d
+// applies to WithHTTPHealthCheck and WithLivenessProbe, overriding their own intervals
+func WithHealthCheckInterval(d time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.checkInterval = d
+	}
+}
+
+// WithReadinessProbe(fn, timeout) - after starting, wait for fn to return true, kill the program if it never does
+func WithReadinessProbe(fn func() bool, timeout time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.readyProbe = fn
+		opt.readyTimeout = timeout
+	}
+}
+
+// WithLivenessProbe(fn, interval, threshold) - once running, call fn every interval, restart the program if it fails threshold times in a row
+// an interval of 0 means every 10 seconds
+func WithLivenessProbe(fn func() bool, interval time.Duration, threshold int) func(*opts) {
+	return func(opt *opts) {
+		opt.liveProbe = fn
+		opt.liveInterval = interval
+		opt.liveFails = threshold
+	}
+}
+
+// WithNotificationFunc(fn) - call fn in the watcher on each lifecycle event
+func WithNotificationFunc(fn func(event DaemonEvent)) func(*opts) {
+	return func(opt *opts) {
+		opt.notify = fn
+	}
+}
+
+// WithEventChan(ch) - send lifecycle events to ch, events are dropped if ch is full
+func WithEventChan(ch chan<- DaemonEvent) func(*opts) {
+	return func(opt *opts) {
+		opt.eventChan = ch
+	}
+}
+
+// WithFileWatch(paths...) - send the reload signal to the program when any of the files change
+func WithFileWatch(paths ...string) func(*opts) {
+	return func(opt *opts) {
+		opt.watchFiles = append(opt.watchFiles, paths...)
+	}
+}
+
+// WithUpdateCheck() - when the executable is replaced, stop the program (SIGTERM) and start the new one
+func WithUpdateCheck() func(*opts) {
+	return func(opt *opts) {
+		opt.updateCheck = true
+	}
+}
+
+// WithSignalFile(sig, filename) - when the file is created, send sig to the program, and remove the file
+func WithSignalFile(sig os.Signal, file string) func(*opts) {
+	return func(opt *opts) {
+		opt.signalFiles = append(opt.signalFiles, signalFile{sig, file})
+	}
+}
+
+// WithRestartOnConfigChange(files...) - when any of the files change, stop the program (SIGTERM) and start it again
+// for programs that only read their config at startup. see also WithFileWatch
+func WithRestartOnConfigChange(files ...string) func(*opts) {
+	return func(opt *opts) {
+		opt.configFiles = append(opt.configFiles, files...)
+	}
+}
+
+// WithReloadSignal(sig) - signal used to tell the program to reload (default SIGHUP)
+func WithReloadSignal(sig os.Signal) func(*opts) {
+	return func(opt *opts) {
+		opt.reloadSignal = sig
+	}
+}
+
+// WithRateLimit(maxPerMinute) - never restart more often than this, regardless of the restart delay
+func WithRateLimit(maxPerMinute float64) func(*opts) {
+	return func(opt *opts) {
+		opt.rateLimit = maxPerMinute
+	}
+}
+
+// WithAuditLog(filename) - append a json record of each watcher decision to the file
+func WithAuditLog(file string) func(*opts) {
+	return func(opt *opts) {
+		opt.auditLog = file
+	}
+}
+
+// WithOTelTracer(tracer) - record a span for each run of the program, with lifecycle events
+func WithOTelTracer(tracer trace.Tracer) func(*opts) {
+	return func(opt *opts) {
+		opt.spans = &spanTracker{tracer: tracer}
+	}
+}
+
+// WithClock(clock) - use a different time source, for testing
+func WithClock(c Clock) func(*opts) {
+	return func(opt *opts) {
+		opt.clock = c
+	}
+}
+
+// WithStderrPattern(re, action) - watch the program's stderr, take action on lines matching re
+func WithStderrPattern(re *regexp.Regexp, action SignalAction) func(*opts) {
+	return func(opt *opts) {
+		opt.stderrPatterns = append(opt.stderrPatterns, stderrPattern{re, action})
+	}
+}
+
+// WithExitMessageFile(filename) - after a crash, log the message the program left with WriteExitMessage
+func WithExitMessageFile(file string) func(*opts) {
+	return func(opt *opts) {
+		opt.exitMsgFile = file
+	}
+}
+
+// WithLogLevel(level) - how chatty the watcher is (default LogLevelInfo)
+func WithLogLevel(l LogLevel) func(*opts) {
+	return func(opt *opts) {
+		opt.logLevel = l
+	}
+}
+
+// WithSignalNotify(ch) - also send signals received by the watcher to ch (which should be buffered)
+func WithSignalNotify(ch chan<- os.Signal) func(*opts) {
+	return func(opt *opts) {
+		opt.sigNotify = ch
+	}
+}
+
+// WithEnvironmentFilter(fn) - remove variables from the program's environment, where fn returns false
+func WithEnvironmentFilter(fn func(key, value string) bool) func(*opts) {
+	return func(opt *opts) {
+		opt.envFilters = append(opt.envFilters, fn)
+	}
+}
+
+// WithStripSecrets(patterns...) - remove variables whose names match from the program's environment
+// with no patterns, removes names containing PASSWORD, TOKEN, or SECRET
+func WithStripSecrets(patterns ...*regexp.Regexp) func(*opts) {
+	if len(patterns) == 0 {
+		patterns = defaultSecrets
+	}
+	return WithEnvironmentFilter(func(key, value string) bool {
+		for _, re := range patterns {
+			if re.MatchString(key) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WithStdoutLineHandler(fn) - in the watcher, call fn with each line the program writes to stdout
+func WithStdoutLineHandler(fn func(line string)) func(*opts) {
+	return func(opt *opts) {
+		opt.stdoutHandlers = append(opt.stdoutHandlers, fn)
+	}
+}
+
+// WithStderrLineHandler(fn) - in the watcher, call fn with each line the program writes to stderr
+func WithStderrLineHandler(fn func(line string)) func(*opts) {
+	return func(opt *opts) {
+		opt.stderrHandlers = append(opt.stderrHandlers, fn)
+	}
+}
+
+// WithExecPath(filename) - run a different program instead of re-executing ourself
+// the program must also call daemon.Ize. problems with it are reported by Ize, before forking
+func WithExecPath(path string) func(*opts) {
+	return func(opt *opts) {
+		fi, err := os.Stat(path)
+		switch {
+		case err != nil:
+			opt.optErr = err
+		case !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0:
+			opt.optErr = fmt.Errorf("%s: not an executable file", path)
+		}
+		opt.execPath = path
+	}
+}
+
+// WithArgs(args) - run the main program with these arguments (not including the program name) instead of ours
+// the watcher keeps our arguments. the main program still finds out it is the main program from the
+// environment, not its arguments, so it needs to call daemon.Ize the same as always
+func WithArgs(args []string) func(*opts) {
+	return func(opt *opts) {
+		opt.args = args
+	}
+}
+
+// WithCrashReport(url, headers) - after each crash, POST a json report, including the last lines of stderr
+func WithCrashReport(url string, headers map[string]string) func(*opts) {
+	return func(opt *opts) {
+		opt.crashURL = url
+		opt.crashHeaders = headers
+	}
+}
+
+// WithFileDescriptorLimit(n) - limit the number of open files (RLIMIT_NOFILE, soft and hard)
+// with WithRuntimeMonitor, a warning is logged when the program has 90% of them open
+func WithFileDescriptorLimit(n uint64) func(*opts) {
+	return func(opt *opts) {
+		opt.fdLimit = n
+	}
+}
+
+// WithThreadLimit(n) - limit the number of threads (RLIMIT_NPROC, soft and hard)
+// the limit is per user, not per process, so it also counts the user's other processes
+// the go runtime dies if it cannot start a thread, so a leak fails fast, and gets restarted
+func WithThreadLimit(n uint64) func(*opts) {
+	return func(opt *opts) {
+		opt.threadLimit = n
+	}
+}
+
+// WithGoroutineLimit(n) - same as WithThreadLimit
+// goroutines are not limited, only the os threads they run on
+// (blocked in syscalls or cgo, each takes a thread)
+func WithGoroutineLimit(n uint64) func(*opts) {
+	return WithThreadLimit(n)
+}
+
+// WithAfterExit(fn) - in the watcher, call fn once the program exits cleanly (exit 0), and we are not restarting
+func WithAfterExit(fn func()) func(*opts) {
+	return func(opt *opts) {
+		opt.afterExit = fn
+	}
+}
+
+// WithDetachTTY() - the program is not a session leader, so opening a terminal device
+// cannot give it a controlling terminal. needs the watcher, so not with WithNoRestart
+func WithDetachTTY() func(*opts) {
+	return func(opt *opts) {
+		opt.detachTTY = true
+	}
+}
+
+// WithMountNamespace() - run the main program in its own mount namespace (linux only)
+func WithMountNamespace() func(*opts) {
+	return func(opt *opts) {
+		opt.mountNS = true
+	}
+}
+
+// WithBindMount(src, dst) - in the program's mount namespace, bind mount src on dst
+// implies WithMountNamespace
+func WithBindMount(src, dst string) func(*opts) {
+	return func(opt *opts) {
+		opt.mountNS = true
+		opt.bindMounts = append(opt.bindMounts, bindMount{src, dst})
+	}
+}
+
+// WithReadOnlyFS(writablePaths...) - run the program in its own mount namespace, with / read-only
+// except for writablePaths. needs CAP_SYS_ADMIN. linux only
+func WithReadOnlyFS(writablePaths ...string) func(*opts) {
+	return func(opt *opts) {
+		opt.mountNS = true
+		opt.readOnlyFS = true
+		opt.writable = writablePaths
+	}
+}
+
+// WithNetworkNamespace(path) - run the main program in the network namespace at path (eg. /var/run/netns/name)
+// or in a new, empty, one if path does not exist. linux only. requires CAP_SYS_ADMIN
+func WithNetworkNamespace(path string) func(*opts) {
+	return func(opt *opts) {
+		opt.netNS = path
+	}
+}
+
+// WithSignalCooldown(sig, dur) - after passing sig to the program, ignore it for dur
+func WithSignalCooldown(sig os.Signal, d time.Duration) func(*opts) {
+	return func(opt *opts) {
+		if opt.sigCooldown == nil {
+			opt.sigCooldown = make(map[os.Signal]time.Duration)
+		}
+		opt.sigCooldown[sig] = d
+	}
+}
+
+// WithSignalPersistence(sig, interval, max) - after passing sig to the program, resend it every interval until it exits
+// after max resends, kill it
+func WithSignalPersistence(sig os.Signal, interval time.Duration, max int) func(*opts) {
+	return func(opt *opts) {
+		if opt.sigPersist == nil {
+			opt.sigPersist = make(map[os.Signal]signalPersist)
+		}
+		opt.sigPersist[sig] = signalPersist{interval: interval, max: max}
+	}
+}
+
+// WithWatcherCPULimit(pct) - keep the watcher's own cpu use under pct percent of a cpu, by pausing before restarts
+func WithWatcherCPULimit(pct float64) func(*opts) {
+	return func(opt *opts) {
+		opt.cpuLimit = pct
+	}
+}
+
+// WithStartAfterSignal(sig) - the watcher waits until it receives sig before starting the program
+func WithStartAfterSignal(sig os.Signal) func(*opts) {
+	return func(opt *opts) {
+		opt.startSignal = sig
+	}
+}
+
+// WithChildEnvSecret(name, value) - pass value to the program on a file descriptor, rather than in the environment
+// name_FD is set to the fd number, the program reads it with ReadSecret(name)
+func WithChildEnvSecret(name, value string) func(*opts) {
+	return func(opt *opts) {
+		opt.secrets = append(opt.secrets, secret{name, value})
+	}
+}
+
+// WithChildCgroupV2(base) - run each start of the program in a new cgroup, base/child-<pid>, removed when it exits
+// base must be a cgroup v2 directory we can create cgroups in. the program is moved in just after it starts
+func WithChildCgroupV2(base string) func(*opts) {
+	return func(opt *opts) {
+		opt.cgroupBase = base
+	}
+}
+
+// WithSubreaper() - orphaned descendants of the program are reparented to the watcher (which reaps them), not to init. linux only
+func WithSubreaper() func(*opts) {
+	return func(opt *opts) {
+		opt.subreaper = true
+	}
+}
+
+// WithZombieReaper() - the watcher reaps any exited children other than the program,
+// for when it is pid 1 in a container. see also WithSubreaper. linux only
+func WithZombieReaper() func(*opts) {
+	return func(opt *opts) {
+		opt.zombieReaper = true
+	}
+}
+
+// WithForkTimeout(dur) - give up, and exit, if starting a process takes longer than dur
+func WithForkTimeout(d time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.forkTimeout = d
+	}
+}
+
+// WithExecPrep(cmd, args...) - run cmd before switching to the background. if it fails, so do we
+func WithExecPrep(cmd string, args ...string) func(*opts) {
+	return func(opt *opts) {
+		opt.prep = append(opt.prep, append([]string{cmd}, args...))
+	}
+}
+
+// WithSystemdWatchdog() - send systemd watchdog keepalives (WatchdogSec=), while the program is running and healthy
+func WithSystemdWatchdog() func(*opts) {
+	return func(opt *opts) {
+		opt.sdWatchdog = true
+	}
+}
+
+// WithEtcdRegistration(endpoints, key, value, ttl) - while the program runs, register key in etcd, on a lease of ttl
+// {pid} in value is replaced with the program's pid. endpoints are etcd's http urls (eg. http://127.0.0.1:2379)
+// the ttl must be at least a second
+func WithEtcdRegistration(endpoints []string, key, value string, ttl time.Duration) func(*opts) {
+	return func(opt *opts) {
+		if ttl < time.Second {
+			opt.optErr = fmt.Errorf("etcd ttl must be at least 1s, not %v", ttl)
+			return
+		}
+		opt.etcd = &etcdRegistration{
+			endpoints: endpoints,
+			key:       key,
+			value:     value,
+			ttl:       ttl,
+			logf:      opt.logf,
+		}
+	}
+}
+
+// WithConsulRegistration(addr, service) - while the program runs, register the service with the consul agent at addr
+// (eg. 127.0.0.1:8500). updated with the new pid on each restart
+func WithConsulRegistration(addr string, service *ConsulServiceDef) func(*opts) {
+	return func(opt *opts) {
+		opt.consul = &consulRegistration{
+			addr:    addr,
+			service: *service,
+			logf:    opt.logf,
+		}
+	}
+}
+
+// WithManagedSocket(path) - the watcher creates a unix socket, and passes it to each run of the program
+// it stays open across restarts. the program gets it with GetManagedSocket(path)
+func WithManagedSocket(path string) func(*opts) {
+	return func(opt *opts) {
+		opt.sockets = append(opt.sockets, path)
+	}
+}
+
+// WithDNSCheck(hostname, timeout) - before each start, wait until hostname resolves
+// each lookup gives up after timeout, and is retried after the restart delay
+func WithDNSCheck(hostname string, timeout time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.dnsCheck = hostname
+		opt.dnsTimeout = timeout
+	}
+}
+
+// WithTLSCertRotation(certPath) - send the reload signal when the certificate changes
+// and warn when it is close to expiring
+func WithTLSCertRotation(certPath string) func(*opts) {
+	return func(opt *opts) {
+		opt.certPath = certPath
+	}
+}
+
+// WithCertExpiryWarning(threshold) - with WithTLSCertRotation, warn when the certificate expires within threshold
+// the default is 7 days
+func WithCertExpiryWarning(threshold time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.certWarn = threshold
+	}
+}
+
+// WithRuntimeMonitor(interval) - periodically log the program's memory and cpu use, at debug level
+// these are also available with WithMetricsAddr. warns if 90% of its file descriptors are open.
+// uses /proc, so linux only
+func WithRuntimeMonitor(interval time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.monInterval = interval
+	}
+}
+
+// WithMetricsAddr(addr) - the watcher serves prometheus metrics on http://addr/metrics
+func WithMetricsAddr(addr string) func(*opts) {
+	return func(opt *opts) {
+		opt.metricsAddr = addr
+	}
+}
+
+// WithPrometheusLabels(labels) - with WithMetricsAddr, add these labels to every metric
+// eg. to tell several daemons on the same host apart
+func WithPrometheusLabels(labels map[string]string) func(*opts) {
+	return func(opt *opts) {
+		l, err := formatLabels(labels)
+		if err != nil {
+			opt.optErr = err
+			return
+		}
+		opt.promLabels = l
+	}
+}
+
+// WithCleanupFunc(fn) - in the watcher, call fn after every exit of the program, before it is restarted
+func WithCleanupFunc(fn func(reason ExitReason)) func(*opts) {
+	return func(opt *opts) {
+		opt.cleanup = fn
+	}
+}
+
+// WithEnvironmentCapture(path) - at startup, save the environment the program will run with to path
+// the file is only readable by us, it may contain secrets
+func WithEnvironmentCapture(path string) func(*opts) {
+	return func(opt *opts) {
+		opt.envCapture = path
+	}
+}
+
+// WithSignalWait(sig) - don't exit the initial process until the daemon sends it sig
+// the watcher sends it once the program first passes its startup checks (WithTCPPortCheck, etc)
+// with WithNoRestart, the program sends it as Ize returns
+func WithSignalWait(sig os.Signal) func(*opts) {
+	return func(opt *opts) {
+		opt.waitSig = sig
+	}
+}
+
+// WithFdCloseOnExec() - at startup, mark every file descriptor other than stdin, stdout, stderr close-on-exec
+// so nothing we inherited leaks into the watcher or program
+func WithFdCloseOnExec() func(*opts) {
+	return func(opt *opts) {
+		opt.fdCloexec = true
+	}
+}
+
+// WithAutoSelectPidDir(dirs...) - if WithPidFile is only a file name, put it in the first writable dir
+// eg. WithAutoSelectPidDir("/run", "/var/run", "/tmp")
+func WithAutoSelectPidDir(candidates ...string) func(*opts) {
+	return func(opt *opts) {
+		opt.pidDirs = candidates
+	}
+}
+
+// WithCrashDump(dir) - each time the program fails, save a crash report in dir/crash-<time>-<pid>.json
+// it has the exit status, recent stderr, and recent audit log entries
+func WithCrashDump(dir string) func(*opts) {
+	return func(opt *opts) {
+		opt.crashDir = dir
+	}
+}
+
+// WithMaxCrashDumps(n) - with WithCrashDump, only keep the most recent n reports
+func WithMaxCrashDumps(n int) func(*opts) {
+	return func(opt *opts) {
+		opt.maxCrashDumps = n
+	}
+}
+
+// WithRestartHook(url) - each time the program is restarted after a crash, POST the crash report to url
+// the restart does not wait for it
+func WithRestartHook(url string) func(*opts) {
+	return func(opt *opts) {
+		opt.hookURL = url
+	}
+}
+
+// WithHookTimeout(d) - give up on the WithRestartHook request after d, the default is 10 seconds
+func WithHookTimeout(d time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.hookTimeout = d
+	}
+}
+
+// WithStateFile(path) - the watcher saves its restart count, failures, and backoff in path when it exits,
+// and a new watcher (eg. after an upgrade) picks up where it left off
+func WithStateFile(path string) func(*opts) {
+	return func(opt *opts) {
+		opt.stateFile = path
+	}
+}
+
+// WithNotifyParent() - don't exit the initial process until the program calls NotifyReady
+// like WithSignalWait(SIGUSR1), but the program decides when it is ready
+func WithNotifyParent() func(*opts) {
+	return func(opt *opts) {
+		opt.waitSig = sigReady
+		opt.notifyParent = true
+	}
+}
+
+// WithMaxStartupTime(d) - kill (and restart) the program if it has not called NotifyReady within d of starting
+func WithMaxStartupTime(d time.Duration) func(*opts) {
+	return func(opt *opts) {
+		opt.maxStartup = d
+	}
+}
+
+// WithDistributedLock(redisAddr, lockKey, ttl) - take a lock in redis before each start, releasing it once the program exits
+// so only one copy runs across all hosts, the others wait. the lock expires after ttl if we stop renewing it
+// the ttl must be at least a second
+func WithDistributedLock(redisAddr, lockKey string, ttl time.Duration) func(*opts) {
+	return func(opt *opts) {
+		if ttl < time.Second {
+			opt.optErr = fmt.Errorf("distributed lock ttl must be at least 1s, not %v", ttl)
+			return
+		}
+		opt.redisLock = newRedisLock(redisAddr, lockKey, ttl)
+	}
+}
+
+// WithExecAsUser(username) - the main program switches to the user, then execs itself to start over clean
+// the watcher keeps running as us
+func WithExecAsUser(username string) func(*opts) {
+	return func(opt *opts) {
+		opt.execUser = username
+	}
+}
+
+// WithNegotiatedPort(envVar) - before each start, find a free tcp port, and pass it to the program in envVar
+// the program gets it with GetNegotiatedPort(envVar). it may be different after a restart
+func WithNegotiatedPort(envVar string) func(*opts) {
+	return func(opt *opts) {
+		opt.portVars = append(opt.portVars, envVar)
+	}
+}
+
+// WithPanicRestart() - with the main program run by daemon.Run(fn), a panic in fn is logged, and the program restarted
+func WithPanicRestart() func(*opts) {
+	return func(opt *opts) {
+		opt.panicRestart = true
+	}
+}
+
+// WithExitOnSignal(sigs...) - SigExiter also exits, successfully, on these signals
+func WithExitOnSignal(sigs ...os.Signal) func(*opts) {
+	return func(opt *opts) {
+		opt.exitSigs = append(opt.exitSigs, sigs...)
+	}
+}
+
+// WithRestartOnSignal(sigs...) - SigExiter exits on these signals, asking the watcher to restart us
+func WithRestartOnSignal(sigs ...os.Signal) func(*opts) {
+	return func(opt *opts) {
+		opt.restartSigs = append(opt.restartSigs, sigs...)
+	}
+}
+
+// WithProxySignals(targetPidFile, sigs...) - in the main program, catch sigs, and send them on to the process in targetPidFile
+func WithProxySignals(targetPidFile string, sigs ...os.Signal) func(*opts) {
+	return func(opt *opts) {
+		opt.proxyPidFile = targetPidFile
+		opt.proxySigs = sigs
+	}
+}
+
+// WithExecAfterCrash(cmd, args...) - after each crash, run cmd, and wait for it, before restarting
+// it gets DAEMON_PID, DAEMON_EXIT_CODE, and DAEMON_SIGNAL (if it was killed) in its environment
+func WithExecAfterCrash(cmd string, args ...string) func(*opts) {
+	return func(opt *opts) {
+		opt.afterCrash = append([]string{cmd}, args...)
+	}
+}
+
+// WithTempDir(path) - set TMPDIR, TEMP, and TMP for the program, creating path if needed
+func WithTempDir(path string) func(*opts) {
+	return func(opt *opts) {
+		opt.tempDir = path
 	}
 }
 
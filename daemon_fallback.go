@@ -0,0 +1,77 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:22 (EDT)
+// Function: watch files by polling
+
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package daemon
+
+import (
+	"os"
+	"time"
+)
+
+const pollInterval = time.Second
+
+type pollWatcher struct {
+	files   map[string]os.FileInfo
+	changes chan string
+	done    chan struct{}
+}
+
+func newFileWatcher(paths []string) (fileWatcher, error) {
+
+	w := &pollWatcher{
+		files:   make(map[string]os.FileInfo),
+		changes: make(chan string, 1),
+		done:    make(chan struct{}),
+	}
+
+	for _, p := range paths {
+		fi, _ := os.Stat(p)
+		w.files[p] = fi
+	}
+
+	go w.run()
+	return w, nil
+}
+
+func (w *pollWatcher) Changes() <-chan string {
+	return w.changes
+}
+
+func (w *pollWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+func (w *pollWatcher) run() {
+
+	defer close(w.changes)
+	tick := time.NewTicker(pollInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick.C:
+		}
+
+		for path, old := range w.files {
+			fi, _ := os.Stat(path)
+			w.files[path] = fi
+
+			if fi == nil || (old != nil && os.SameFile(old, fi) &&
+				fi.ModTime().Equal(old.ModTime()) && fi.Size() == old.Size()) {
+				continue
+			}
+			select {
+			case w.changes <- path:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:22 (EDT)
+// Function: watch files using inotify
+
+//go:build linux
+// +build linux
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// watch the directories, so we notice files being replaced (renamed over)
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE
+
+type inotifyWatcher struct {
+	f       *os.File
+	dirs    map[int32]string
+	files   map[string]bool
+	changes chan string
+}
+
+func newFileWatcher(paths []string) (fileWatcher, error) {
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &inotifyWatcher{
+		f:       os.NewFile(uintptr(fd), "inotify"),
+		dirs:    make(map[int32]string),
+		files:   make(map[string]bool),
+		changes: make(chan string, 1),
+	}
+
+	watching := make(map[string]bool)
+	for _, p := range paths {
+		w.files[p] = true
+		dir := filepath.Dir(p)
+		if watching[dir] {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(fd, dir, inotifyMask)
+		if err != nil {
+			w.f.Close()
+			return nil, err
+		}
+		watching[dir] = true
+		w.dirs[int32(wd)] = dir
+	}
+
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Changes() <-chan string {
+	return w.changes
+}
+
+func (w *inotifyWatcher) Close() error {
+	return w.f.Close()
+}
+
+func (w *inotifyWatcher) read() {
+
+	defer close(w.changes)
+	buf := make([]byte, 64*1024)
+
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			off += syscall.SizeofInotifyEvent
+			end := off + int(ev.Len)
+			if end > n {
+				break
+			}
+			name := strings.TrimRight(string(buf[off:end]), "\x00")
+			off = end
+
+			path := filepath.Join(w.dirs[ev.Wd], name)
+			if !w.files[path] {
+				continue
+			}
+			select {
+			case w.changes <- path:
+			default:
+			}
+		}
+	}
+}
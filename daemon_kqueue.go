@@ -0,0 +1,135 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:22 (EDT)
+// Function: watch files using kqueue
+
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package daemon
+
+import (
+	"syscall"
+	"time"
+)
+
+const kqueueMask = syscall.NOTE_WRITE | syscall.NOTE_EXTEND | syscall.NOTE_DELETE | syscall.NOTE_RENAME
+
+type kqueueWatcher struct {
+	kq      int
+	files   map[string]int // -1 if not currently open
+	changes chan string
+	done    chan struct{}
+}
+
+func newFileWatcher(paths []string) (fileWatcher, error) {
+
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(kq)
+
+	w := &kqueueWatcher{
+		kq:      kq,
+		files:   make(map[string]int),
+		changes: make(chan string, 1),
+		done:    make(chan struct{}),
+	}
+
+	for _, p := range paths {
+		w.files[p] = -1
+		w.open(p)
+	}
+
+	go w.run()
+	return w, nil
+}
+
+func (w *kqueueWatcher) Changes() <-chan string {
+	return w.changes
+}
+
+func (w *kqueueWatcher) Close() error {
+	close(w.done)
+	return nil
+}
+
+func (w *kqueueWatcher) open(path string) bool {
+
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return false
+	}
+
+	ev := make([]syscall.Kevent_t, 1)
+	syscall.SetKevent(&ev[0], fd, syscall.EVFILT_VNODE, syscall.EV_ADD|syscall.EV_CLEAR)
+	ev[0].Fflags = kqueueMask
+
+	if _, err := syscall.Kevent(w.kq, ev, nil, nil); err != nil {
+		syscall.Close(fd)
+		return false
+	}
+
+	w.files[path] = fd
+	return true
+}
+
+func (w *kqueueWatcher) changed(path string) {
+	select {
+	case w.changes <- path:
+	default:
+	}
+}
+
+func (w *kqueueWatcher) run() {
+
+	defer close(w.changes)
+	events := make([]syscall.Kevent_t, 16)
+	// wake up periodically to look for files that have been replaced
+	timeout := syscall.NsecToTimespec(int64(time.Second))
+
+	for {
+		select {
+		case <-w.done:
+			for _, fd := range w.files {
+				if fd != -1 {
+					syscall.Close(fd)
+				}
+			}
+			syscall.Close(w.kq)
+			return
+		default:
+		}
+
+		n, err := syscall.Kevent(w.kq, nil, events, &timeout)
+		if err != nil && err != syscall.EINTR {
+			return
+		}
+
+		for i := 0; i < n; i++ {
+			fd := int(events[i].Ident)
+			for path, pfd := range w.files {
+				if pfd != fd {
+					continue
+				}
+				if events[i].Fflags&(syscall.NOTE_DELETE|syscall.NOTE_RENAME) != 0 {
+					// closing it removes it from the kqueue
+					syscall.Close(fd)
+					w.files[path] = -1
+					if !w.open(path) {
+						// check again later
+						break
+					}
+				}
+				w.changed(path)
+			}
+		}
+
+		for path, fd := range w.files {
+			if fd == -1 && w.open(path) {
+				w.changed(path)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:38 (EDT)
+// Function: unix specific bits
+
+//go:build !windows
+// +build !windows
+
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// we can leave the terminal, and run in the background
+const canDetach = true
+
+// WithNotifyParent
+const sigReady = syscall.SIGUSR1
+
+func setsid() {
+	syscall.Setsid()
+}
+
+// hold an advisory lock on file, until unlock is called
+func lockFile(file string) (func(), error) {
+
+	for {
+		f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0666)
+		if err != nil {
+			return nil, err
+		}
+		lk := syscall.Flock_t{Type: syscall.F_WRLCK}
+		if err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLKW, &lk); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		// whoever had it may have removed it, then we have a lock on nothing
+		held, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if cur, err := os.Stat(file); err == nil && os.SameFile(held, cur) {
+			return func() { f.Close() }, nil
+		}
+		f.Close()
+	}
+}
+
+// is the process still there?
+func pidAlive(pid int) error {
+	if err := syscall.Kill(pid, 0); err != nil && err != syscall.EPERM {
+		return err
+	}
+	return nil
+}
+
+// cpu time used by the watcher
+func selfCPU() time.Duration {
+
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// WithFdCloseOnExec. don't leak anything but stdin, stdout, stderr to our children
+func closeOnExec() {
+
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		fds, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range fds {
+			if fd, err := strconv.Atoi(f.Name()); err == nil && fd > 2 {
+				syscall.CloseOnExec(fd)
+			}
+		}
+		return
+	}
+}
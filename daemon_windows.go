@@ -0,0 +1,58 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:38 (EDT)
+// Function: enough windows to compile and run in the foreground
+
+//go:build windows
+// +build windows
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// no sessions to escape, Ize stays in the foreground
+const canDetach = false
+
+// WithNotifyParent, there is no SIGUSR1. unused, we never wait for it
+const sigReady = syscall.Signal(0x1e)
+
+func setsid() {}
+
+// nothing to lock, only one of us runs in the foreground
+func lockFile(file string) (func(), error) {
+	return func() {}, nil
+}
+
+// is the process still there?
+func pidAlive(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	p.Release()
+	return nil
+}
+
+// not tracked, WithWatcherCPULimit never pauses
+func selfCPU() time.Duration {
+	return 0
+}
+
+// handles are not inherited unless asked for
+func closeOnExec() {}
+
+func (o *opts) execAsUser() error {
+	return errors.New("exec as user is not supported on windows")
+}
+
+func (o *opts) setRlimits() error {
+	if o.fdLimit != 0 || o.threadLimit != 0 {
+		return errors.New("resource limits are not supported on windows")
+	}
+	return nil
+}
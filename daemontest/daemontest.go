@@ -0,0 +1,148 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:51 (EDT)
+// Function: simulate the watcher in tests
+
+// run the daemon watcher in-process, with fake programs, to test restart behavior
+package daemontest
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jaw0/go-daemon"
+)
+
+type DaemonSim struct {
+	t       *testing.T
+	started chan *fakeProcess
+	done    chan int
+	cur     *fakeProcess
+	lock    sync.Mutex
+	nextPid int
+}
+
+// SimulateDaemon(t, WithOpts...) - start the watcher, wait for the first (fake) program to start
+func SimulateDaemon(t *testing.T, opts ...daemon.Option) *DaemonSim {
+
+	s := &DaemonSim{
+		t:       t,
+		started: make(chan *fakeProcess, 16),
+		done:    make(chan int, 1),
+		nextPid: 1000,
+	}
+
+	go func() {
+		s.done <- daemon.WatchWith(s.start, opts...)
+	}()
+
+	t.Cleanup(func() {
+		// let the watcher finish
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.cur != nil {
+			s.cur.exit(daemon.ExitStatus{Code: 0})
+		}
+	})
+
+	s.WaitRestart()
+	return s
+}
+
+func (s *DaemonSim) start() (daemon.Process, error) {
+
+	s.lock.Lock()
+	s.nextPid++
+	p := &fakeProcess{
+		pid:  s.nextPid,
+		done: make(chan daemon.ExitStatus, 1),
+	}
+	s.lock.Unlock()
+
+	s.started <- p
+	return p, nil
+}
+
+// Exit(code) - the running program exits
+func (s *DaemonSim) Exit(code int) {
+	s.current().exit(daemon.ExitStatus{Code: code})
+}
+
+// Pid() - the pid of the running program
+func (s *DaemonSim) Pid() int {
+	return s.current().pid
+}
+
+// Signals() - signals the watcher has sent to the running program
+func (s *DaemonSim) Signals() []os.Signal {
+	return s.current().signals()
+}
+
+// WaitRestart() - block until the watcher starts the program again
+func (s *DaemonSim) WaitRestart() {
+
+	select {
+	case p := <-s.started:
+		s.lock.Lock()
+		s.cur = p
+		s.lock.Unlock()
+	case code := <-s.done:
+		s.done <- code
+		s.t.Fatalf("watcher exited (%d) instead of restarting", code)
+	}
+}
+
+// WaitExit() - block until the watcher is done, return its exit status
+func (s *DaemonSim) WaitExit() int {
+	code := <-s.done
+	s.done <- code
+	return code
+}
+
+func (s *DaemonSim) current() *fakeProcess {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.cur
+}
+
+type fakeProcess struct {
+	pid  int
+	done chan daemon.ExitStatus
+	lock sync.Mutex
+	sigs []os.Signal
+}
+
+func (p *fakeProcess) Pid() int {
+	return p.pid
+}
+
+func (p *fakeProcess) Signal(sig os.Signal) error {
+
+	p.lock.Lock()
+	p.sigs = append(p.sigs, sig)
+	p.lock.Unlock()
+
+	if sig == os.Kill {
+		p.exit(daemon.ExitStatus{Code: -1, Signal: sig})
+	}
+	return nil
+}
+
+func (p *fakeProcess) Wait() (daemon.ExitStatus, error) {
+	return <-p.done, nil
+}
+
+func (p *fakeProcess) exit(st daemon.ExitStatus) {
+	select {
+	case p.done <- st:
+	default:
+		// already exited
+	}
+}
+
+func (p *fakeProcess) signals() []os.Signal {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]os.Signal(nil), p.sigs...)
+}
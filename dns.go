@@ -0,0 +1,35 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:33 (EDT)
+// Function: wait for dns before starting
+
+package daemon
+
+import (
+	"context"
+	"net"
+)
+
+// keep trying until the name resolves
+func (w *watcher) waitForDNS() {
+
+	opt := w.opt
+
+	for {
+		err := opt.lookupDNS()
+		if err == nil {
+			return
+		}
+		opt.logf(LogLevelWarn, "%s: dns check failed: %v, retrying in %v", w.prog, err, opt.restartDelay)
+		opt.clock.Sleep(opt.restartDelay)
+	}
+}
+
+func (o *opts) lookupDNS() error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.dnsTimeout)
+	defer cancel()
+
+	_, err := net.DefaultResolver.LookupHost(ctx, o.dnsCheck)
+	return err
+}
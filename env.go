@@ -0,0 +1,68 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:54 (EDT)
+// Function: environment of the processes we start
+
+package daemon
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+var defaultSecrets = []*regexp.Regexp{regexp.MustCompile(`(?i)PASSWORD|TOKEN|SECRET`)}
+
+// the environment for the processes we start
+func (o *opts) environ() []string {
+
+	var env []string
+
+	for _, kv := range os.Environ() {
+		k, v := kv, ""
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			k, v = kv[:i], kv[i+1:]
+		}
+		if k == respawnVar || k == crashVar || k == socketVar || k == setupVar || o.isPortVar(k) {
+			// set for each run
+			continue
+		}
+		if o.tempDir != "" && isTempVar(k) {
+			continue
+		}
+		if !isControlVar(k) && !o.envAllowed(k, v) {
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	if o.tempDir != "" {
+		env = append(env, "TMPDIR="+o.tempDir, "TEMP="+o.tempDir, "TMP="+o.tempDir)
+	}
+
+	return env
+}
+
+// ours, passed on whatever the user's filters say
+func isControlVar(k string) bool {
+	switch k {
+	case ENVVAR, parentVar, pidClaimVar, execUserVar, launchdVar:
+		return true
+	}
+	return false
+}
+
+// WithTempDir replaces these
+func isTempVar(k string) bool {
+	return k == "TMPDIR" || k == "TEMP" || k == "TMP"
+}
+
+func (o *opts) envAllowed(k, v string) bool {
+
+	for _, fn := range o.envFilters {
+		if !fn(k, v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 03:00 (EDT)
+// Function: test the environment we pass on
+
+package daemon
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEnvironFilterKeepsControlVars(t *testing.T) {
+
+	for _, k := range []string{ENVVAR, parentVar, pidClaimVar, execUserVar, launchdVar, "DAEMON_TEST_OTHER"} {
+		os.Setenv(k, "1")
+		defer os.Unsetenv(k)
+	}
+
+	// allow nothing
+	o := newOpts([]optFunc{
+		WithEnvironmentFilter(func(key, value string) bool { return false }),
+		WithStripSecrets(),
+	})
+
+	have := map[string]bool{}
+	for _, kv := range o.environ() {
+		have[kv[:strings.IndexByte(kv, '=')]] = true
+	}
+
+	for _, k := range []string{ENVVAR, parentVar, pidClaimVar, execUserVar, launchdVar} {
+		if !have[k] {
+			t.Errorf("%s was filtered out", k)
+		}
+	}
+	if have["DAEMON_TEST_OTHER"] {
+		t.Errorf("DAEMON_TEST_OTHER was not filtered out")
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:39 (EDT)
+// Function: save the environment, for later comparison
+
+package daemon
+
+import (
+	"sort"
+	"strings"
+)
+
+// WithEnvironmentCapture. write the program's environment, one KEY=VALUE per line
+func (o *opts) captureEnv() error {
+
+	env := o.environ()
+	sort.Strings(env)
+
+	return writeFileAtomic(o.envCapture, []byte(strings.Join(env, "\n")+"\n"), 0600, false, nil)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:16 (EDT)
+// Function: errors
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DaemonError - an error from the daemon. Code is the exit status used if it is fatal
+type DaemonError interface {
+	error
+	Code() int
+}
+
+// Error - the errors we return. compare with errors.Is(err, ErrAlreadyRunning), etc
+type Error struct {
+	code int
+	msg  string
+	err  error // what went wrong, if we know
+}
+
+var (
+	ErrStartFailed    = &Error{code: 2, msg: "cannot start"}
+	ErrAlreadyRunning = &Error{code: 3, msg: "already running"}
+	ErrStalePidFile   = &Error{code: 4, msg: "stale pid file"}
+	ErrNotRunning     = &Error{code: 5, msg: "not running"}
+	ErrTimeout        = &Error{code: 6, msg: "timed out"}
+	ErrKilled         = &Error{code: 7, msg: "killed"} // the watcher had to kill the program, and stopped
+)
+
+func (e *Error) Error() string {
+	if e.err != nil {
+		return e.msg + ": " + e.err.Error()
+	}
+	return e.msg
+}
+
+func (e *Error) Code() int {
+	return e.code
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// the same sort of error, whatever the details
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.code == e.code
+}
+
+// add the details to one of ours
+func wrapErr(kind *Error, err error) error {
+	var de DaemonError
+	if errors.As(err, &de) {
+		// already one of ours
+		return err
+	}
+	return &Error{code: kind.code, msg: kind.msg, err: err}
+}
+
+func wrapErrf(kind *Error, format string, args ...interface{}) error {
+	return &Error{code: kind.code, msg: kind.msg, err: fmt.Errorf(format, args...)}
+}
+
+// the error for an exit status
+func errorKind(code int) *Error {
+	for _, e := range []*Error{ErrAlreadyRunning, ErrStalePidFile, ErrNotRunning, ErrTimeout, ErrKilled} {
+		if e.code == code {
+			return e
+		}
+	}
+	return ErrStartFailed
+}
+
+// the exit status for the error
+func errorCode(err error) int {
+	var de DaemonError
+	if errors.As(err, &de) {
+		return de.Code()
+	}
+	return ErrStartFailed.code
+}
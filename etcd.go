@@ -0,0 +1,188 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:30 (EDT)
+// Function: register in etcd, using its json gateway
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type etcdRegistration struct {
+	endpoints []string
+	key       string
+	value     string
+	ttl       time.Duration
+	logf      func(LogLevel, string, ...interface{})
+	queue     serialQueue
+
+	lock  sync.Mutex
+	lease string // id, once granted
+	pid   int
+	stop  chan struct{}
+}
+
+func (r *etcdRegistration) event(ev DaemonEvent) {
+
+	switch ev.EventType {
+	case EventStarted:
+		// don't hold up the watcher
+		r.queue.run(func() { r.register(ev.PID) })
+	case EventStopped:
+		r.queue.wait(r.deregister)
+	}
+}
+
+// put the key, with the program's pid, on our lease
+func (r *etcdRegistration) register(pid int) {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pid = pid
+	if err := r.put(); err != nil {
+		r.logf(LogLevelWarn, "etcd registration: %v", err)
+	}
+
+	if r.stop == nil {
+		r.stop = make(chan struct{})
+		go r.keepAlive(r.stop)
+	}
+}
+
+func (r *etcdRegistration) deregister() {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	if r.lease == "" {
+		return
+	}
+
+	// the key goes with the lease
+	if err := r.call("/v3/lease/revoke", map[string]interface{}{"ID": r.lease}, nil); err != nil {
+		r.logf(LogLevelWarn, "etcd deregistration: %v", err)
+	}
+	r.lease = ""
+}
+
+// lock must be held
+func (r *etcdRegistration) put() error {
+
+	if r.lease == "" {
+		var res struct {
+			ID string `json:"ID"`
+		}
+		secs := int64((r.ttl + time.Second - 1) / time.Second)
+		if err := r.call("/v3/lease/grant", map[string]interface{}{"TTL": secs}, &res); err != nil {
+			return err
+		}
+		if res.ID == "" {
+			return fmt.Errorf("no lease granted")
+		}
+		r.lease = res.ID
+	}
+
+	value := strings.Replace(r.value, "{pid}", strconv.Itoa(r.pid), -1)
+
+	return r.call("/v3/kv/put", map[string]interface{}{
+		"key":   base64.StdEncoding.EncodeToString([]byte(r.key)),
+		"value": base64.StdEncoding.EncodeToString([]byte(value)),
+		"lease": r.lease,
+	}, nil)
+}
+
+func (r *etcdRegistration) keepAlive(stop chan struct{}) {
+
+	tick := time.NewTicker(r.ttl / 3)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+		}
+
+		r.lock.Lock()
+		if r.stop == stop {
+			r.refresh()
+		}
+		r.lock.Unlock()
+	}
+}
+
+// lock must be held
+func (r *etcdRegistration) refresh() {
+
+	if r.lease != "" {
+		var res struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		err := r.call("/v3/lease/keepalive", map[string]interface{}{"ID": r.lease}, &res)
+		if err == nil && res.Result.TTL != "" && res.Result.TTL != "0" {
+			return
+		}
+		if err != nil {
+			r.logf(LogLevelWarn, "etcd keepalive: %v", err)
+			return
+		}
+		// expired, start over
+		r.lease = ""
+	}
+
+	if err := r.put(); err != nil {
+		r.logf(LogLevelWarn, "etcd registration: %v", err)
+	}
+}
+
+// POST to the first endpoint that answers
+func (r *etcdRegistration) call(path string, req interface{}, res interface{}) error {
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	c := &http.Client{Timeout: 5 * time.Second}
+
+	for _, ep := range r.endpoints {
+		var resp *http.Response
+		resp, err = c.Post(strings.TrimRight(ep, "/")+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("%s: %s", ep, resp.Status)
+			resp.Body.Close()
+			continue
+		}
+
+		if res != nil {
+			err = json.NewDecoder(resp.Body).Decode(res)
+		}
+		resp.Body.Close()
+		return err
+	}
+
+	if err == nil {
+		err = fmt.Errorf("no etcd endpoints")
+	}
+	return err
+}
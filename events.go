@@ -0,0 +1,141 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:43 (EDT)
+// Function: lifecycle events
+
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType int
+
+const (
+	EventStarted    EventType = iota // program was started
+	EventStopped                     // program exited cleanly, watcher is done
+	EventCrashed                     // program exited unexpectedly
+	EventRestarting                  // program is about to be restarted
+	EventKilled                      // watcher killed the program
+	EventAlert                       // something the program said, see WithStderrPattern
+)
+
+var eventNames = []string{"started", "stopped", "crashed", "restarting", "killed", "alert"}
+
+func (e EventType) String() string {
+	if int(e) < len(eventNames) {
+		return eventNames[e]
+	}
+	return "unknown"
+}
+
+// ExitReason is passed to WithCleanupFunc
+type ExitReason int
+
+const (
+	ExitReasonClean       ExitReason = iota // program exited successfully
+	ExitReasonCrash                         // program exited with an error
+	ExitReasonKilled                        // watcher killed the program
+	ExitReasonMaxRestarts                   // program exited with an error, and will not be restarted again
+	ExitReasonSignal                        // program was terminated by a signal
+)
+
+var exitReasonNames = []string{"clean", "crash", "killed", "max-restarts", "signal"}
+
+func (r ExitReason) String() string {
+	if int(r) < len(exitReasonNames) {
+		return exitReasonNames[r]
+	}
+	return "unknown"
+}
+
+// DaemonEvent is delivered to WithNotificationFunc and WithEventChan
+type DaemonEvent struct {
+	EventType    EventType
+	Timestamp    time.Time
+	ExitCode     int // valid for stopped, crashed, killed; -1 if terminated by a signal
+	RestartCount int
+	PID          int    // pid of the program
+	Reason       string // why the watcher killed the program, the alert, or the program's exit message
+}
+
+func (o *opts) event(typ EventType, pid int, code int, restarts int, reason string) {
+
+	if o.notify == nil && o.eventChan == nil && o.spans == nil && o.etcd == nil && o.consul == nil {
+		return
+	}
+
+	ev := DaemonEvent{
+		EventType:    typ,
+		Timestamp:    o.clock.Now(),
+		ExitCode:     code,
+		RestartCount: restarts,
+		PID:          pid,
+		Reason:       reason,
+	}
+
+	if o.spans != nil {
+		o.spans.event(ev)
+	}
+	if o.etcd != nil {
+		o.etcd.event(ev)
+	}
+	if o.consul != nil {
+		o.consul.event(ev)
+	}
+	if o.notify != nil {
+		o.notify(ev)
+	}
+	if o.eventChan != nil {
+		select {
+		case o.eventChan <- ev:
+		default:
+			// don't stall the watcher
+		}
+	}
+}
+
+// the watcher is exiting, however it got there
+func (o *opts) eventsDone() {
+
+	if o.spans != nil {
+		o.spans.finish(o.clock.Now())
+	}
+	if o.etcd != nil {
+		o.etcd.queue.wait(o.etcd.deregister)
+	}
+	if o.consul != nil {
+		o.consul.queue.wait(o.consul.deregister)
+	}
+}
+
+// run the registration calls one at a time, in order, without holding up the watcher
+type serialQueue struct {
+	once sync.Once
+	q    chan func()
+}
+
+func (s *serialQueue) run(fn func()) {
+
+	s.once.Do(func() {
+		s.q = make(chan func(), 16)
+		go func() {
+			for fn := range s.q {
+				fn()
+			}
+		}()
+	})
+	s.q <- fn
+}
+
+// run fn after everything already queued, and wait for it
+func (s *serialQueue) wait(fn func()) {
+
+	done := make(chan struct{})
+	s.run(func() {
+		fn()
+		close(done)
+	})
+	<-done
+}
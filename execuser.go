@@ -0,0 +1,61 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:49 (EDT)
+// Function: become another user, and start over
+
+//go:build !windows
+// +build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// WithExecAsUser. in the main program, switch to the user and exec ourself. does not return, unless it fails
+func (o *opts) execAsUser() error {
+
+	u, err := user.Lookup(o.execUser)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return fmt.Errorf("user %s: invalid uid %q", o.execUser, u.Uid)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return fmt.Errorf("user %s: invalid gid %q", o.execUser, u.Gid)
+	}
+
+	var groups []int
+	if gids, err := u.GroupIds(); err == nil {
+		for _, g := range gids {
+			if n, err := strconv.Atoi(g); err == nil {
+				groups = append(groups, n)
+			}
+		}
+	}
+
+	// groups first, we can't once we are no longer root
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("setgroups: %v", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("setgid %d: %v", gid, err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("setuid %d: %v", uid, err)
+	}
+
+	prog, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	os.Setenv(execUserVar, "1")
+	return syscall.Exec(prog, os.Args, os.Environ())
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:53 (EDT)
+// Function: the program's last words
+
+package daemon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// set by Ize, so the program knows where to write
+var exitMessageFile string
+
+// WriteExitMessage(msg) - record why we are exiting, for the watcher to log, see WithExitMessageFile
+func WriteExitMessage(msg string) error {
+
+	if exitMessageFile == "" {
+		return errors.New("no exit message file configured")
+	}
+	return ioutil.WriteFile(exitMessageFile, []byte(msg), 0644)
+}
+
+// read (and remove) the exit message left by the program
+func (o *opts) readExitMessage() string {
+
+	if o.exitMsgFile == "" {
+		return ""
+	}
+
+	buf, err := ioutil.ReadFile(o.exitMsgFile)
+	if err != nil {
+		return ""
+	}
+	os.Remove(o.exitMsgFile)
+	return strings.TrimSpace(string(buf))
+}
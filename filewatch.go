@@ -0,0 +1,60 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:48 (EDT)
+// Function: watch files for changes
+
+package daemon
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// the platform specific watcher reports the path of each file that changes
+type fileWatcher interface {
+	Changes() <-chan string
+	Close() error
+}
+
+// editors + config management often write files in several steps
+const settleTime = 100 * time.Millisecond
+
+func absPaths(paths []string) []string {
+
+	var abs []string
+	for _, p := range paths {
+		if a, err := filepath.Abs(p); err == nil {
+			p = a
+		}
+		abs = append(abs, filepath.Clean(p))
+	}
+	return abs
+}
+
+// call fn whenever any of the files change
+func (o *opts) onFileChange(paths []string, fn func()) {
+
+	w, err := newFileWatcher(absPaths(paths))
+	if err != nil {
+		o.logf(LogLevelError, "cannot watch files: %v", err)
+		return
+	}
+
+	for path := range w.Changes() {
+		// let things settle, then coalesce
+		time.Sleep(settleTime)
+		drain(w.Changes())
+		o.logf(LogLevelDebug, "%s changed", path)
+		fn()
+	}
+}
+
+func drain(c <-chan string) {
+	for {
+		select {
+		case <-c:
+		default:
+			return
+		}
+	}
+}
@@ -1,3 +1,9 @@
 module github.com/jaw0/go-daemon
 
 go 1.15
+
+require (
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	golang.org/x/sys v0.0.0-20210423082822-04245dca01da
+)
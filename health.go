@@ -0,0 +1,188 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:41 (EDT)
+// Function: health checks of the running program
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// is there anything for the watcher to check?
+func (o *opts) wantMonitor() bool {
+	return o.tcpCheck != "" || o.httpCheck != "" || o.readyProbe != nil || o.liveProbe != nil
+}
+
+// is there anything to wait for before the program is ready?
+func (o *opts) wantStartupCheck() bool {
+	return o.tcpCheck != "" || o.readyProbe != nil
+}
+
+// watch the running program, kill it if it is unhealthy
+func (o *opts) monitor(c *child) {
+
+	stop := c.stop
+
+	// starting up
+	if o.tcpCheck != "" && !o.waitUntil(stop, o.tcpTimeout, o.checkTCPPort) {
+		c.kill("port " + o.tcpCheck + " never became available")
+		return
+	}
+	if o.readyProbe != nil && !o.waitUntil(stop, o.readyTimeout, func(time.Duration) bool { return o.readyProbe() }) {
+		c.kill("readiness probe never succeeded")
+		return
+	}
+
+	if c.ready != nil && !o.notifyParent {
+		// otherwise, once it calls NotifyReady
+		c.ready()
+	}
+
+	// running
+	var wg sync.WaitGroup
+
+	if o.httpCheck != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !o.keepChecking(stop, o.checkEvery(o.httpInterval), o.failsAllowed(o.healthFails), c.healthCheck(o.checkHTTP)) {
+				c.kill("health check " + o.httpCheck + " failed")
+			}
+		}()
+	}
+	if o.liveProbe != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !o.keepChecking(stop, o.checkEvery(o.liveInterval), o.failsAllowed(o.liveFails), c.healthCheck(o.liveProbe)) {
+				c.kill("liveness probe failed")
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// if the check doesn't say how often
+const defaultCheckInterval = 10 * time.Second
+
+// or how long to wait
+const defaultCheckTimeout = 5 * time.Second
+
+// WithHealthCheckInterval overrides the check's own
+func (o *opts) checkEvery(d time.Duration) time.Duration {
+	if o.checkInterval > 0 {
+		return o.checkInterval
+	}
+	if d <= 0 {
+		return defaultCheckInterval
+	}
+	return d
+}
+
+// as does WithHealthCheckFailThreshold
+func (o *opts) failsAllowed(n int) int {
+	if o.checkFails > 0 {
+		return o.checkFails
+	}
+	return n
+}
+
+// WithMaxStartupTime. kill it if it doesn't call NotifyReady in time
+func (o *opts) startupDeadline(c *child) {
+
+	select {
+	case <-c.stop:
+	case <-c.notified:
+	case <-o.clock.After(o.maxStartup):
+		c.kill(fmt.Sprintf("did not call NotifyReady within %v", o.maxStartup))
+	}
+}
+
+// try the check with exponential backoff until it succeeds or we time out
+func (o *opts) waitUntil(stop chan struct{}, timeout time.Duration, check func(left time.Duration) bool) bool {
+
+	deadline := o.clock.Now().Add(timeout)
+	delay := 100 * time.Millisecond
+
+	for {
+		left := deadline.Sub(o.clock.Now())
+		if left <= 0 {
+			return false
+		}
+
+		if check(left) {
+			return true
+		}
+
+		left = deadline.Sub(o.clock.Now())
+		if delay > left {
+			delay = left
+		}
+		select {
+		case <-stop:
+			return false
+		case <-o.clock.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// is the port accepting connections?
+func (o *opts) checkTCPPort(timeout time.Duration) bool {
+
+	c, err := net.DialTimeout("tcp", o.tcpCheck, timeout)
+	if err != nil {
+		return false
+	}
+	c.Close()
+	return true
+}
+
+// GET the health url, anything 2xx or 3xx is healthy
+func (o *opts) checkHTTP() bool {
+
+	timeout := o.httpTimeout
+	if timeout <= 0 {
+		// a hung server would stall the checks forever
+		timeout = defaultCheckTimeout
+	}
+	c := &http.Client{Timeout: timeout}
+	res, err := c.Get(o.httpCheck)
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+
+	return res.StatusCode >= 200 && res.StatusCode < 400
+}
+
+// run the check periodically, until it fails too many times in a row
+// returns true if the program exited on its own
+func (o *opts) keepChecking(stop chan struct{}, interval time.Duration, threshold int, check func() bool) bool {
+
+	fails := 0
+
+	for {
+		select {
+		case <-stop:
+			return true
+		case <-o.clock.After(interval):
+		}
+
+		if check() {
+			fails = 0
+			continue
+		}
+
+		fails++
+		if fails >= threshold {
+			return false
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 02:30 (EDT)
+// Function: test the health checks
+
+package daemon_test
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaw0/go-daemon"
+	"github.com/jaw0/go-daemon/daemontest"
+	"github.com/jaw0/go-daemon/testutil"
+)
+
+func wasKilled(s *daemontest.DaemonSim) bool {
+	for _, sig := range s.Signals() {
+		if sig == os.Kill {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLivenessProbe(t *testing.T) {
+
+	var healthy int32 = 1
+	probe := func() bool { return atomic.LoadInt32(&healthy) != 0 }
+
+	clock := testutil.NewFakeClock(epoch)
+	events := make(chan daemon.DaemonEvent, 16)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock), daemon.WithEventChan(events),
+		daemon.WithRestartDelay(time.Second), daemon.WithLivenessProbe(probe, 10*time.Second, 2))
+
+	// healthy, left alone
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(10 * time.Second)
+	}
+	if wasKilled(s) {
+		t.Fatalf("healthy program was killed")
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Second)
+	if wasKilled(s) {
+		t.Fatalf("killed after one failure")
+	}
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Second)
+
+	// then restarted
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+
+	for {
+		select {
+		case ev := <-events:
+			if ev.EventType != daemon.EventKilled {
+				continue
+			}
+			if ev.Reason != "liveness probe failed" {
+				t.Fatalf("killed for %q", ev.Reason)
+			}
+			return
+		default:
+			t.Fatalf("no killed event")
+		}
+	}
+}
+
+func TestLivenessProbeDefaultInterval(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock),
+		daemon.WithLivenessProbe(func() bool { return false }, 0, 1))
+
+	clock.BlockUntil(1)
+	clock.Advance(10*time.Second - time.Millisecond)
+	if wasKilled(s) {
+		t.Fatalf("checked too soon")
+	}
+	clock.Advance(time.Millisecond)
+	clock.BlockUntil(1)
+	if !wasKilled(s) {
+		t.Fatalf("not killed after failing")
+	}
+}
+
+func TestHealthCheckFailThreshold(t *testing.T) {
+
+	code := daemon.WatchWith(func() (daemon.Process, error) {
+		t.Fatalf("started with an invalid threshold")
+		return nil, nil
+	}, daemon.WithHealthCheckFailThreshold(0))
+
+	if code != 2 {
+		t.Fatalf("watcher exited %d, expected 2", code)
+	}
+}
+
+func TestMaxStartupTime(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock),
+		daemon.WithRestartDelay(time.Second), daemon.WithMaxStartupTime(5*time.Second))
+
+	// it never calls NotifyReady
+	clock.BlockUntil(1)
+	clock.Advance(5*time.Second - time.Millisecond)
+	if wasKilled(s) {
+		t.Fatalf("killed too soon")
+	}
+	clock.Advance(time.Millisecond)
+
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+}
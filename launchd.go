@@ -0,0 +1,32 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:39 (EDT)
+// Function: running under launchd
+
+package daemon
+
+import (
+	"os"
+)
+
+// set by the initial process, so the watcher + program agree with it
+const launchdVar = "_dlaunchd"
+
+// launchd sets XPC_SERVICE_NAME to the job's label for the programs it starts
+// (it is "0" in a login session, and other things in app terminals, eg. vscode's),
+// and is their parent. we also accept LAUNCH_DAEMON, for plists that set it
+func underLaunchd(mode string) bool {
+
+	if mode != "" {
+		return os.Getenv(launchdVar) != ""
+	}
+
+	if os.Getenv("LAUNCH_DAEMON") == "" {
+		name := os.Getenv("XPC_SERVICE_NAME")
+		if name == "" || name == "0" || os.Getppid() != 1 {
+			return false
+		}
+	}
+	os.Setenv(launchdVar, "1")
+	return true
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:54 (EDT)
+// Function: diagnostic output
+
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// stdout is always /dev/null once we are in the background, stderr can be kept with WithStderr
+func (o *opts) logf(l LogLevel, format string, args ...interface{}) {
+
+	if l < o.logLevel {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// number of open file descriptors, for debugging leaks
+func openFDs() int {
+
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if fds, err := ioutil.ReadDir(dir); err == nil {
+			return len(fds)
+		}
+	}
+	return -1
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:34 (EDT)
+// Function: prometheus metrics from the watcher
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WithPrometheusLabels. {key="value",...}, sorted, so it is the same every time
+func formatLabels(labels map[string]string) (string, error) {
+
+	if len(labels) == 0 {
+		return "", nil
+	}
+
+	var names []string
+	for k := range labels {
+		if !labelName.MatchString(k) || strings.HasPrefix(k, "__") {
+			return "", fmt.Errorf("invalid prometheus label name %q", k)
+		}
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var l []string
+	for _, k := range names {
+		l = append(l, fmt.Sprintf(`%s="%s"`, k, labelEscaper.Replace(labels[k])))
+	}
+	return "{" + strings.Join(l, ",") + "}", nil
+}
+
+// serve /metrics in the prometheus text format
+func (w *watcher) serveMetrics() {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", w.writeMetrics)
+
+	err := http.ListenAndServe(w.opt.metricsAddr, mux)
+	w.opt.logf(LogLevelError, "metrics server on %s: %v", w.opt.metricsAddr, err)
+}
+
+func (w *watcher) writeMetrics(rw http.ResponseWriter, req *http.Request) {
+
+	up := 0
+	if w.cur.alive() {
+		up = 1
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintf(rw, "# HELP daemon_up whether the program is running\n")
+	fmt.Fprintf(rw, "# TYPE daemon_up gauge\n")
+	fmt.Fprintf(rw, "daemon_up%s %d\n", w.opt.promLabels, up)
+	fmt.Fprintf(rw, "# HELP daemon_restarts_total how many times the program has been restarted\n")
+	fmt.Fprintf(rw, "# TYPE daemon_restarts_total counter\n")
+	fmt.Fprintf(rw, "daemon_restarts_total%s %d\n", w.opt.promLabels, RespawnCount())
+
+	s, ok := w.stats.get()
+	if !ok {
+		return
+	}
+	fmt.Fprintf(rw, "# HELP daemon_child_rss_bytes resident memory of the program\n")
+	fmt.Fprintf(rw, "# TYPE daemon_child_rss_bytes gauge\n")
+	fmt.Fprintf(rw, "daemon_child_rss_bytes%s %d\n", w.opt.promLabels, s.rss)
+	fmt.Fprintf(rw, "# HELP daemon_child_cpu_seconds_total cpu time used by the program since it started\n")
+	fmt.Fprintf(rw, "# TYPE daemon_child_cpu_seconds_total counter\n")
+	fmt.Fprintf(rw, "daemon_child_cpu_seconds_total%s %g\n", w.opt.promLabels, s.cpu.Seconds())
+}
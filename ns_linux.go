@@ -0,0 +1,122 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:13 (EDT)
+// Function: linux namespaces
+
+//go:build linux
+// +build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// find problems before we fork
+func (o *opts) checkNamespaces() error {
+
+	for _, dir := range o.writable {
+		if !fileExists(dir) {
+			return fmt.Errorf("writable path %s does not exist", dir)
+		}
+	}
+	return nil
+}
+
+// how to start the main program
+func (o *opts) sysProcAttr() *syscall.SysProcAttr {
+
+	var flags uintptr
+
+	if o.mountNS {
+		flags |= syscall.CLONE_NEWNS
+	}
+	if o.netNS != "" && !fileExists(o.netNS) {
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	if flags == 0 {
+		return nil
+	}
+	return &syscall.SysProcAttr{Cloneflags: flags}
+}
+
+// start the main program in the network namespace
+// namespaces belong to threads, so we join it on a thread of its own, and fork from there.
+// the thread is never unlocked, and goes away when we are done with it
+func (o *opts) startInNamespace(start func() (Process, error)) (Process, error) {
+
+	if o.netNS == "" || !fileExists(o.netNS) {
+		// nothing to join
+		return start()
+	}
+
+	f, err := os.Open(o.netNS)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p Process
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+
+		if err = unix.Setns(int(f.Fd()), unix.CLONE_NEWNET); err != nil {
+			err = fmt.Errorf("cannot join network namespace %s: %v", o.netNS, err)
+			return
+		}
+		p, err = start()
+	}()
+
+	<-done
+	return p, err
+}
+
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+// in the main program, set up the namespaces we were started in
+func (o *opts) enterNamespaces() error {
+
+	if !o.mountNS {
+		return nil
+	}
+
+	// keep our mounts to ourself
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("cannot make mounts private: %v", err)
+	}
+
+	for _, m := range o.bindMounts {
+		if err := syscall.Mount(m.src, m.dst, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("cannot bind mount %s on %s: %v", m.src, m.dst, err)
+		}
+	}
+
+	if !o.readOnlyFS {
+		return nil
+	}
+
+	// mount the writable places on themselves, so they stay writable
+	for _, dir := range o.writable {
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("cannot bind mount %s: %v", dir, err)
+		}
+	}
+	// only / itself, other mounts (/proc, /dev, ...) are unchanged
+	if err := syscall.Mount("", "/", "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+		return fmt.Errorf("cannot remount / read-only: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:13 (EDT)
+// Function: no namespaces here
+
+//go:build !linux
+// +build !linux
+
+package daemon
+
+import (
+	"errors"
+	"syscall"
+)
+
+// find problems before we fork
+func (o *opts) checkNamespaces() error {
+
+	if o.mountNS {
+		return errors.New("mount namespaces are only supported on linux")
+	}
+	if o.netNS != "" {
+		return errors.New("network namespaces are only supported on linux")
+	}
+	return nil
+}
+
+func (o *opts) sysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func (o *opts) startInNamespace(start func() (Process, error)) (Process, error) {
+	return start()
+}
+
+func (o *opts) enterNamespaces() error {
+	return nil
+}
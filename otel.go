@@ -0,0 +1,72 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:49 (EDT)
+// Function: opentelemetry spans for each run of the program
+
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// one span per run, from start until the watcher decides what to do next
+// events come from the watcher, and from the stderr reader (alerts)
+type spanTracker struct {
+	tracer trace.Tracer
+	lock   sync.Mutex
+	span   trace.Span
+}
+
+func (t *spanTracker) event(ev DaemonEvent) {
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	attrs := trace.WithAttributes(
+		attribute.Int("pid", ev.PID),
+		attribute.Int("restarts", ev.RestartCount),
+		attribute.Int("exit_code", ev.ExitCode),
+	)
+
+	if ev.EventType == EventStarted {
+		_, t.span = t.tracer.Start(context.Background(), "daemon.run",
+			trace.WithTimestamp(ev.Timestamp),
+			trace.WithAttributes(attribute.Int("pid", ev.PID), attribute.Int("restarts", ev.RestartCount)))
+	}
+	if t.span == nil {
+		return
+	}
+
+	if ev.Reason != "" {
+		t.span.AddEvent(ev.EventType.String(), attrs, trace.WithTimestamp(ev.Timestamp),
+			trace.WithAttributes(attribute.String("reason", ev.Reason)))
+	} else {
+		t.span.AddEvent(ev.EventType.String(), attrs, trace.WithTimestamp(ev.Timestamp))
+	}
+
+	switch ev.EventType {
+	case EventCrashed, EventKilled:
+		t.span.SetStatus(codes.Error, ev.EventType.String())
+	case EventRestarting, EventStopped:
+		t.span.End(trace.WithTimestamp(ev.Timestamp))
+		t.span = nil
+	}
+}
+
+// the watcher is done, end the span, if it is still open
+func (t *spanTracker) finish(now time.Time) {
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.span != nil {
+		t.span.End(trace.WithTimestamp(now))
+		t.span = nil
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:52 (EDT)
+// Function: watch the program's output
+
+package daemon
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SignalAction is what to do when WithStderrPattern matches
+type SignalAction int
+
+const (
+	ActionRestart SignalAction = iota // kill + restart the program
+	ActionStop                        // kill the program, and stop. the watcher exits with ErrKilled
+	ActionAlert                       // send an EventAlert
+)
+
+type stderrPattern struct {
+	re     *regexp.Regexp
+	action SignalAction
+}
+
+func (o *opts) wantStdoutPipe() bool {
+	return len(o.stdoutHandlers) > 0 || (o.stdoutFile != "" && o.tee)
+}
+
+func (o *opts) wantStderrPipe() bool {
+	return len(o.stderrPatterns) > 0 || len(o.stderrHandlers) > 0 || (o.stderrFile != "" && o.tee) || o.crashURL != "" || o.crashDir != "" || o.hookURL != ""
+}
+
+func openOutputFile(file string) (*os.File, error) {
+	return os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+// open the output files, for the life of the watcher
+func (w *watcher) openOutput() {
+
+	var err error
+
+	if w.opt.stdoutFile != "" {
+		if w.outFile, err = openOutputFile(w.opt.stdoutFile); err != nil {
+			w.opt.logf(LogLevelError, "cannot open stdout file: %v", err)
+		}
+	}
+	if w.opt.stderrFile != "" {
+		if w.errFile, err = openOutputFile(w.opt.stderrFile); err != nil {
+			w.opt.logf(LogLevelError, "cannot open stderr file: %v", err)
+		}
+	}
+}
+
+// where the program's output goes, when we are reading it
+func (w *watcher) stdoutDst() io.Writer {
+
+	var dst io.Writer = ioutil.Discard
+	if w.opt.foreground {
+		dst = os.Stdout
+	}
+	return w.teeDst(w.outFile, dst)
+}
+
+func (w *watcher) stderrDst() io.Writer {
+
+	var dst io.Writer = ioutil.Discard
+	if w.opt.keepStderr || w.opt.foreground {
+		dst = os.Stderr
+	}
+	return w.teeDst(w.errFile, dst)
+}
+
+func (w *watcher) teeDst(f *os.File, dst io.Writer) io.Writer {
+
+	switch {
+	case f == nil:
+		return dst
+	case w.opt.tee && dst != ioutil.Discard:
+		return io.MultiWriter(f, dst)
+	default:
+		return f
+	}
+}
+
+func (w *watcher) scanStdout(f *os.File, c *child) {
+	scanLines(f, w.stdoutDst(), func(line string) {
+		for _, fn := range w.opt.stdoutHandlers {
+			fn(line)
+		}
+	})
+}
+
+func (w *watcher) scanStderr(f *os.File, c *child) {
+	defer close(c.tailDone)
+	scanLines(f, w.stderrDst(), func(line string) {
+		if c.tail != nil {
+			c.tail.add(line)
+		}
+		for _, fn := range w.opt.stderrHandlers {
+			fn(line)
+		}
+		w.opt.matchStderr(line, c)
+	})
+}
+
+// the last few lines
+type lineRing struct {
+	lock  sync.Mutex
+	lines []string
+	max   int
+}
+
+func (r *lineRing) add(line string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lines = append(r.lines, line)
+	if len(r.lines) > r.max {
+		r.lines = r.lines[len(r.lines)-r.max:]
+	}
+}
+
+func (r *lineRing) get() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.lines...)
+}
+
+// read the program's output line by line, passing it on to where it would have gone
+func scanLines(f *os.File, dst io.Writer, each func(string)) {
+
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			each(strings.TrimRight(line, "\r\n"))
+			dst.Write([]byte(line))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (o *opts) matchStderr(line string, c *child) {
+
+	for _, pat := range o.stderrPatterns {
+		if !pat.re.MatchString(line) {
+			continue
+		}
+
+		why := "stderr matched " + pat.re.String()
+		switch pat.action {
+		case ActionRestart:
+			c.kill(why)
+		case ActionStop:
+			c.killAndQuit(why, ErrKilled)
+		case ActionAlert:
+			o.event(EventAlert, c.proc.Pid(), 0, 0, line)
+		}
+	}
+}
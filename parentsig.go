@@ -0,0 +1,111 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:41 (EDT)
+// Function: tell the launching process we are up
+
+package daemon
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+)
+
+// the pid of the initial process, waiting to hear from us
+const parentVar = "_dparent"
+
+// with WithNotifyParent, set in the main program
+var notifyReady func()
+
+// NotifyReady() - with WithNotifyParent, tell the initial process that we are ready, so it can exit
+// only the first call does anything
+func NotifyReady() {
+	if notifyReady != nil {
+		notifyReady()
+	}
+}
+
+// in the initial process, before starting the daemon
+func (o *opts) prepareSignalWait() chan os.Signal {
+
+	if o.waitSig == nil {
+		return nil
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, o.waitSig)
+	os.Setenv(parentVar, strconv.Itoa(os.Getpid()))
+	return c
+}
+
+// wait for the signal, or for the daemon to fail
+func (o *opts) waitForSignal(p Process, c chan os.Signal) {
+
+	exited := make(chan ExitStatus, 1)
+	go func() {
+		st, _ := p.Wait()
+		exited <- st
+	}()
+
+	select {
+	case <-c:
+	case st := <-exited:
+		kind := ErrStartFailed
+		if !o.justOne {
+			// the watcher exits with one of ours
+			kind = errorKind(st.Code)
+		}
+		o.fatal(wrapErrf(kind, "exited before starting: %s", st))
+	}
+}
+
+// in the watcher or main program, learn who is waiting
+func (o *opts) loadParent() {
+	if n, err := strconv.Atoi(os.Getenv(parentVar)); err == nil {
+		atomic.StoreInt32(&o.parentPid, int32(n))
+	}
+	os.Unsetenv(parentVar)
+}
+
+// tell them we're up. only once
+func (o *opts) signalParent(sig os.Signal) {
+
+	pid := int(atomic.SwapInt32(&o.parentPid, 0))
+	if pid == 0 || sig == nil {
+		return
+	}
+
+	if p, err := os.FindProcess(pid); err == nil {
+		o.logf(LogLevelDebug, "sending %v to pid %d", sig, pid)
+		p.Signal(sig)
+	}
+}
+
+// in the main program, who is waiting to hear from us?
+func (o *opts) setNotifyReady() {
+
+	o.loadParent()
+
+	switch {
+	case o.justOne && o.notifyParent:
+		// the initial process
+		notifyReady = func() { o.signalParent(o.waitSig) }
+	case o.justOne:
+		o.signalParent(o.waitSig)
+	case o.notifyParent || o.maxStartup > 0:
+		// the watcher
+		notifyReady = func() { o.signalParent(sigReady) }
+	}
+}
+
+// WithNotifyParent. each run of the program tells us when it is ready, and we tell them
+// a run may crash before it gets there, so we hold on to their pid until one does
+// WithMaxStartupTime also wants to know
+func (o *opts) passParent(pa *os.ProcAttr) {
+
+	if !o.notifyParent && o.maxStartup <= 0 {
+		return
+	}
+	pa.Env = append(pa.Env, parentVar+"="+strconv.Itoa(os.Getpid()))
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:42 (EDT)
+// Function: find somewhere to put the pid file
+
+package daemon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WithAutoSelectPidDir. a bare pid file name goes in the first writable candidate
+func (o *opts) selectPidDir() {
+
+	if o.pidFile == "" || len(o.pidDirs) == 0 || filepath.Base(o.pidFile) != o.pidFile {
+		return
+	}
+
+	for _, dir := range o.pidDirs {
+		if dirWritable(dir) {
+			o.pidFile = filepath.Join(dir, o.pidFile)
+			return
+		}
+	}
+
+	o.optErr = errors.New("no writable directory for pid file " + o.pidFile)
+}
+
+// can we create files there?
+func dirWritable(dir string) bool {
+
+	f, err := ioutil.TempFile(dir, ".pidtest")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
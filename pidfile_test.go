@@ -0,0 +1,146 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 03:04 (EDT)
+// Function: test the exclusive pidfile
+
+//go:build !windows
+// +build !windows
+
+package daemon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// not a pid anyone can have
+const deadPid = 999999999
+
+func exclPidOpts(t *testing.T, optfn ...optFunc) *opts {
+
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "test.pid")
+	o := newOpts(append([]optFunc{WithPidFile(file), WithExclusivePidFileWrite()}, optfn...))
+	if o.optErr != nil {
+		t.Fatal(o.optErr)
+	}
+	return o
+}
+
+func putPid(t *testing.T, file string, pid int) {
+
+	t.Helper()
+	if err := ioutil.WriteFile(file, []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectPid(t *testing.T, file string, want int) {
+
+	t.Helper()
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.SplitN(string(buf), "\n", 2)[0])
+	if pid != want {
+		t.Fatalf("pidfile has %q, expected pid %d", buf, want)
+	}
+}
+
+func TestPidFileCreate(t *testing.T) {
+
+	o := exclPidOpts(t)
+	if err := o.createPidFile(1234); err != nil {
+		t.Fatal(err)
+	}
+	expectPid(t, o.pidFile, 1234)
+}
+
+func TestPidFileRunning(t *testing.T) {
+
+	o := exclPidOpts(t)
+	putPid(t, o.pidFile, os.Getpid())
+
+	err := o.createPidFile(1234)
+	if !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("got %v, expected ErrAlreadyRunning", err)
+	}
+	expectPid(t, o.pidFile, os.Getpid())
+}
+
+func TestPidFileStale(t *testing.T) {
+
+	o := exclPidOpts(t)
+	putPid(t, o.pidFile, deadPid)
+
+	if err := o.createPidFile(1234); err != nil {
+		t.Fatal(err)
+	}
+	expectPid(t, o.pidFile, 1234)
+}
+
+func TestPidFileExpired(t *testing.T) {
+
+	o := exclPidOpts(t, WithPidFileExpiry(time.Hour))
+	putPid(t, o.pidFile, os.Getpid())
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(o.pidFile, old, old)
+
+	if err := o.createPidFile(1234); err != nil {
+		t.Fatal(err)
+	}
+	expectPid(t, o.pidFile, 1234)
+}
+
+func TestPidFileClaimed(t *testing.T) {
+
+	// held for us by the initial process, which is still running
+	o := exclPidOpts(t)
+	o.pidClaim = os.Getpid()
+	putPid(t, o.pidFile, os.Getpid())
+
+	if err := o.createPidFile(1234); err != nil {
+		t.Fatal(err)
+	}
+	expectPid(t, o.pidFile, 1234)
+}
+
+func TestPidFileClaimTakenOver(t *testing.T) {
+
+	// our claim went stale, and someone else has it now
+	o := exclPidOpts(t)
+	o.pidClaim = deadPid
+	putPid(t, o.pidFile, os.Getpid())
+
+	err := o.createPidFile(1234)
+	if !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("got %v, expected ErrAlreadyRunning", err)
+	}
+	expectPid(t, o.pidFile, os.Getpid())
+}
+
+func TestPidFileRemove(t *testing.T) {
+
+	o := exclPidOpts(t)
+	if err := o.createPidFile(os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+
+	// not ours
+	o.removePidFileOf(1234)
+	expectPid(t, o.pidFile, os.Getpid())
+
+	o.removePidFileOf(os.Getpid())
+	for _, file := range []string{o.pidFile, o.pidLockFile()} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", file)
+		}
+	}
+}
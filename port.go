@@ -0,0 +1,52 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:49 (EDT)
+// Function: find a free port for the program
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// WithNegotiatedPort. let the system pick a port, and tell the program
+func (o *opts) addPorts(pa *os.ProcAttr) error {
+
+	for _, name := range o.portVars {
+		l, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return err
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		// it is free, the program has to grab it before someone else does
+		l.Close()
+		pa.Env = append(pa.Env, name+"="+strconv.Itoa(port))
+	}
+	return nil
+}
+
+func (o *opts) isPortVar(k string) bool {
+	for _, name := range o.portVars {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNegotiatedPort(envVar) - in the main program, the port picked for it with WithNegotiatedPort
+func GetNegotiatedPort(envVar string) (int, error) {
+
+	v := os.Getenv(envVar)
+	if v == "" {
+		return 0, fmt.Errorf("no negotiated port in %s", envVar)
+	}
+	port, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port in %s: %q", envVar, v)
+	}
+	return port, nil
+}
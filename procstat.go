@@ -0,0 +1,159 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:34 (EDT)
+// Function: sample the program's cpu + memory use
+
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// USER_HZ, the units of the cpu times in /proc/<pid>/stat. it is 100 everywhere that matters
+const clockTicks = 100
+
+type procStats struct {
+	rss int64         // bytes
+	cpu time.Duration // user + system
+}
+
+// the most recent sample
+type statsBox struct {
+	lock  sync.Mutex
+	valid bool
+	stats procStats
+}
+
+func (b *statsBox) set(s procStats, valid bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.stats = s
+	b.valid = valid
+}
+
+func (b *statsBox) get() (procStats, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.stats, b.valid
+}
+
+// WithRuntimeMonitor. log the program's resource use periodically
+func (w *watcher) runtimeMonitor() {
+
+	opt := w.opt
+	tick := time.NewTicker(opt.monInterval)
+	defer tick.Stop()
+
+	fdWarned := 0 // pid we already warned about
+
+	for range tick.C {
+		pid := w.cur.pid()
+		if pid == 0 {
+			w.stats.set(procStats{}, false)
+			continue
+		}
+
+		// a leak shows up well before the program falls over
+		if n, max, err := readFdUsage(pid); err == nil && max > 0 {
+			if n*10 >= max*9 {
+				if fdWarned != pid {
+					opt.logf(LogLevelWarn, "%s: pid %d has %d of %d file descriptors open", w.prog, pid, n, max)
+					fdWarned = pid
+				}
+			} else if fdWarned == pid {
+				fdWarned = 0
+			}
+		}
+
+		s, err := readProcStats(pid)
+		if err != nil {
+			opt.logf(LogLevelDebug, "cannot read stats for pid %d: %v", pid, err)
+			w.stats.set(procStats{}, false)
+			continue
+		}
+		w.stats.set(s, true)
+		opt.logf(LogLevelDebug, "%s: pid %d, rss %d KB, cpu %v", w.prog, pid, s.rss/1024, s.cpu)
+	}
+}
+
+func readProcStats(pid int) (procStats, error) {
+
+	var s procStats
+
+	// the command name can contain anything, the fields we want follow the last ')'
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return s, err
+	}
+	i := strings.LastIndexByte(string(data), ')')
+	if i < 0 {
+		return s, fmt.Errorf("cannot parse /proc/%d/stat", pid)
+	}
+	// state is field 3, utime + stime are 14 + 15
+	f := strings.Fields(string(data[i+1:]))
+	if len(f) < 13 {
+		return s, fmt.Errorf("cannot parse /proc/%d/stat", pid)
+	}
+	utime, _ := strconv.ParseInt(f[11], 10, 64)
+	stime, _ := strconv.ParseInt(f[12], 10, 64)
+	s.cpu = time.Duration(utime+stime) * time.Second / clockTicks
+
+	fd, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return s, err
+	}
+	defer fd.Close()
+
+	scan := bufio.NewScanner(fd)
+	for scan.Scan() {
+		// VmRSS:	    1234 kB
+		f := strings.Fields(scan.Text())
+		if len(f) >= 2 && f[0] == "VmRSS:" {
+			kb, _ := strconv.ParseInt(f[1], 10, 64)
+			s.rss = kb * 1024
+			break
+		}
+	}
+
+	return s, nil
+}
+
+// open file descriptors, and the soft limit on them
+func readFdUsage(pid int) (int, int, error) {
+
+	ents, err := ioutil.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fd, err := os.Open(fmt.Sprintf("/proc/%d/limits", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer fd.Close()
+
+	scan := bufio.NewScanner(fd)
+	for scan.Scan() {
+		// Max open files            1024                 4096                 files
+		line := scan.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		f := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(f) == 0 {
+			break
+		}
+		// "unlimited" => 0, nothing to warn about
+		max, _ := strconv.Atoi(f[0])
+		return len(ents), max, nil
+	}
+
+	return len(ents), 0, nil
+}
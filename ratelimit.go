@@ -0,0 +1,54 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:48 (EDT)
+// Function: limit restart rate
+
+package daemon
+
+import (
+	"time"
+)
+
+type tokenBucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+}
+
+func newTokenBucket(perMinute float64, clock Clock) *tokenBucket {
+
+	burst := perMinute
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:   perMinute / 60,
+		burst:  burst,
+		tokens: burst,
+		last:   clock.Now(),
+		clock:  clock,
+	}
+}
+
+// wait until a token is available, and take it
+func (b *tokenBucket) wait() {
+
+	now := b.clock.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	b.last = now
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+
+	if b.tokens < 1 {
+		need := (1 - b.tokens) / b.rate
+		b.clock.Sleep(time.Duration(need * float64(time.Second)))
+		b.last = b.clock.Now()
+		b.tokens = 1
+	}
+
+	b.tokens--
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:27 (EDT)
+// Function: reap orphans, as a subreaper or pid 1
+
+//go:build linux
+// +build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const pAll = 0 // waitid idtype
+
+// reap our exited children, other than the program
+// as the subreaper, orphaned descendants of the program are reparented to us
+// as pid 1 (WithZombieReaper, in a container) they are anyway
+func (w *watcher) startReaper(subreaper bool) error {
+
+	if subreaper {
+		if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+			return err
+		}
+	}
+
+	sigchld := make(chan os.Signal, 1)
+	signal.Notify(sigchld, syscall.SIGCHLD)
+
+	go func() {
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-sigchld:
+			case <-tick.C:
+				// in case the program was in the way last time
+			}
+			w.reap()
+		}
+	}()
+
+	return nil
+}
+
+// wait for any exited orphans. the program itself is left for its Wait
+func (w *watcher) reap() {
+
+	w.reapLock.Lock()
+	defer w.reapLock.Unlock()
+
+	for {
+		pid := waitable()
+		if pid <= 0 || pid == w.cur.pid() {
+			return
+		}
+
+		var ws syscall.WaitStatus
+		syscall.Wait4(pid, &ws, syscall.WNOHANG, nil)
+		w.opt.logf(LogLevelDebug, "reaped orphan pid %d, %s", pid, waitString(ws))
+	}
+}
+
+// the pid of an exited child, without reaping it
+func waitable() int {
+
+	var info [128]byte // siginfo_t
+	_, _, errno := syscall.Syscall6(unix.SYS_WAITID, pAll, 0, uintptr(unsafe.Pointer(&info[0])),
+		unix.WEXITED|unix.WNOHANG|unix.WNOWAIT, 0, 0)
+	if errno != 0 {
+		return 0
+	}
+
+	// si_pid follows 3 ints, aligned for the union
+	off := 12
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		off = 16
+	}
+	return int(*(*int32)(unsafe.Pointer(&info[off])))
+}
+
+func waitString(ws syscall.WaitStatus) string {
+	if ws.Signaled() {
+		return "signal: " + ws.Signal().String()
+	}
+	return fmt.Sprintf("exit status %d", ws.ExitStatus())
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:27 (EDT)
+// Function: no reaper here
+
+//go:build !linux
+// +build !linux
+
+package daemon
+
+import "errors"
+
+func (w *watcher) startReaper(subreaper bool) error {
+	return errors.New("reaping orphans is only supported on linux")
+}
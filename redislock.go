@@ -0,0 +1,211 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:47 (EDT)
+// Function: only run one copy, across hosts, using a lock in redis
+
+package daemon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const redisTimeout = 5 * time.Second
+
+// delete or extend the key, but only if it is still ours
+const (
+	redisRelease = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+	redisExtend  = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+)
+
+type redisLock struct {
+	addr  string
+	key   string
+	ttl   time.Duration
+	token string // who holds it
+}
+
+func newRedisLock(addr, key string, ttl time.Duration) *redisLock {
+
+	host, _ := os.Hostname()
+	return &redisLock{
+		addr:  addr,
+		key:   key,
+		ttl:   ttl,
+		token: fmt.Sprintf("%s:%d:%x", host, os.Getpid(), time.Now().UnixNano()),
+	}
+}
+
+func (l *redisLock) retry() time.Duration {
+	if d := l.ttl / 3; d > 100*time.Millisecond {
+		return d
+	}
+	return 100 * time.Millisecond
+}
+
+// SET NX PX is SETNX + EXPIRE, atomically
+func (l *redisLock) tryLock() (bool, error) {
+
+	res, err := redisDo(l.addr, "SET", l.key, l.token, "NX", "PX", strconv.FormatInt(int64(l.ttl/time.Millisecond), 10))
+	if err != nil {
+		return false, err
+	}
+	return res == "OK", nil
+}
+
+func (l *redisLock) extend() (bool, error) {
+
+	res, err := redisDo(l.addr, "EVAL", redisExtend, "1", l.key, l.token, strconv.FormatInt(int64(l.ttl/time.Millisecond), 10))
+	if err != nil {
+		return false, err
+	}
+	return res == int64(1), nil
+}
+
+func (l *redisLock) release() error {
+	_, err := redisDo(l.addr, "EVAL", redisRelease, "1", l.key, l.token)
+	return err
+}
+
+// WithDistributedLock. wait until we hold the lock
+// returns false if we are told to stop instead
+func (w *watcher) waitForLock() bool {
+
+	l := w.opt.redisLock
+	waiting := false
+
+	for {
+		ok, err := l.tryLock()
+		if ok {
+			if waiting {
+				w.opt.logf(LogLevelInfo, "%s: acquired lock %s", w.prog, l.key)
+			}
+			return true
+		}
+		if err != nil {
+			w.opt.logf(LogLevelWarn, "%s: redis lock: %v", w.prog, err)
+		} else if !waiting {
+			w.opt.logf(LogLevelInfo, "%s: waiting for lock %s", w.prog, l.key)
+		}
+		waiting = true
+
+		select {
+		case n := <-w.sigchan:
+			switch n {
+			case syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
+				return false
+			}
+		case <-time.After(l.retry()):
+		}
+	}
+}
+
+// keep the lock while the program runs. if we lose it, so does the program
+func (w *watcher) holdLock(c *child) {
+
+	l := w.opt.redisLock
+	tick := time.NewTicker(l.retry())
+	defer tick.Stop()
+	last := time.Now()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-tick.C:
+		}
+
+		ok, err := l.extend()
+		switch {
+		case ok:
+			last = time.Now()
+			continue
+		case err == nil:
+			c.kill("lost lock " + l.key)
+			return
+		case time.Since(last) >= l.ttl:
+			c.kill(fmt.Sprintf("cannot renew lock %s: %v", l.key, err))
+			return
+		}
+		w.opt.logf(LogLevelWarn, "%s: redis lock: %v", w.prog, err)
+	}
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// a minimal redis client. one command per connection
+func redisDo(addr string, args ...string) (interface{}, error) {
+
+	conn, err := net.DialTimeout("tcp", addr, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(redisTimeout))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	return redisReply(bufio.NewReader(conn))
+}
+
+func redisReply(r *bufio.Reader) (interface{}, error) {
+
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		res := make([]interface{}, n)
+		for i := range res {
+			if res[i], err = redisReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("redis: invalid reply %q", line)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:56 (EDT)
+// Function: count restarts
+
+package daemon
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// tells the main program how many times it has been restarted
+const respawnVar = "_drespawn"
+
+// and how many times in a row it has failed
+const crashVar = "_dcrashes"
+
+var respawns int32
+var crashes int32
+
+// RespawnCount() - how many times the watcher has restarted the main program
+// works in both the watcher and the main program
+func RespawnCount() int {
+	return int(atomic.LoadInt32(&respawns))
+}
+
+// RestartCount() - how many times in a row the main program has failed, and been restarted
+// unlike RespawnCount, this starts over once it stays up for WithMinUptime
+// works in both the watcher and the main program
+func RestartCount() int {
+	return int(atomic.LoadInt32(&crashes))
+}
+
+// in the main program, learn the counts from the watcher
+func loadRespawnCount() {
+	if n, err := strconv.Atoi(os.Getenv(respawnVar)); err == nil {
+		atomic.StoreInt32(&respawns, int32(n))
+	}
+	if n, err := strconv.Atoi(os.Getenv(crashVar)); err == nil {
+		atomic.StoreInt32(&crashes, int32(n))
+	}
+}
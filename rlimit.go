@@ -0,0 +1,35 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:00 (EDT)
+// Function: resource limits
+
+//go:build !windows
+// +build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// set limits before we fork, so the watcher and program inherit them
+func (o *opts) setRlimits() error {
+
+	if o.fdLimit != 0 {
+		if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, newRlimit(o.fdLimit)); err != nil {
+			return fmt.Errorf("cannot set file descriptor limit %d: %v", o.fdLimit, err)
+		}
+	}
+
+	if o.threadLimit != 0 {
+		if rlimitNproc < 0 {
+			return fmt.Errorf("thread limit not supported on this system")
+		}
+		if err := syscall.Setrlimit(rlimitNproc, newRlimit(o.threadLimit)); err != nil {
+			return fmt.Errorf("cannot set thread limit %d: %v", o.threadLimit, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:00 (EDT)
+// Function: rlimits are signed here
+
+//go:build dragonfly || freebsd
+// +build dragonfly freebsd
+
+package daemon
+
+import "syscall"
+
+func newRlimit(n uint64) *syscall.Rlimit {
+	return &syscall.Rlimit{Cur: int64(n), Max: int64(n)}
+}
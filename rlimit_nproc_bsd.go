@@ -0,0 +1,12 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:09 (EDT)
+// Function: RLIMIT_NPROC on bsd + linux/sparc
+
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd || (linux && sparc64)
+// +build darwin dragonfly freebsd netbsd openbsd linux,sparc64
+
+package daemon
+
+// not in package syscall
+const rlimitNproc = 7
@@ -0,0 +1,12 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:09 (EDT)
+// Function: RLIMIT_NPROC on linux
+
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le && !sparc64
+// +build linux,!mips,!mipsle,!mips64,!mips64le,!sparc64
+
+package daemon
+
+// not in package syscall
+const rlimitNproc = 6
@@ -0,0 +1,12 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:09 (EDT)
+// Function: no RLIMIT_NPROC here
+
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package daemon
+
+// not supported
+const rlimitNproc = -1
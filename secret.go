@@ -0,0 +1,82 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:24 (EDT)
+// Function: pass secrets on file descriptors
+
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+)
+
+type secret struct {
+	name  string
+	value string
+}
+
+var secretLock sync.Mutex
+var secretCache = make(map[string]string)
+
+// pass each secret on a pipe, after the stdio files
+// returns our copies of the read ends, to close once the program has them
+func (o *opts) addSecrets(pa *os.ProcAttr) ([]*os.File, error) {
+
+	var ends []*os.File
+
+	for _, s := range o.secrets {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			for _, f := range ends {
+				f.Close()
+			}
+			return nil, err
+		}
+
+		pa.Env = append(pa.Env, fmt.Sprintf("%s_FD=%d", s.name, len(pa.Files)))
+		pa.Files = append(pa.Files, pr)
+		ends = append(ends, pr)
+
+		// may not fit in the pipe, don't wait for the program to read it
+		o.secretsSent.Add(1)
+		go func(w *os.File, v string) {
+			defer o.secretsSent.Done()
+			w.WriteString(v)
+			w.Close()
+		}(pw, s.value)
+	}
+
+	return ends, nil
+}
+
+// ReadSecret(name) - in the main program, read the secret passed with WithChildEnvSecret
+func ReadSecret(name string) (string, error) {
+
+	secretLock.Lock()
+	defer secretLock.Unlock()
+
+	if v, ok := secretCache[name]; ok {
+		return v, nil
+	}
+
+	env := name + "_FD"
+	fd, err := strconv.Atoi(os.Getenv(env))
+	if err != nil {
+		return "", fmt.Errorf("no secret %s", name)
+	}
+
+	f := os.NewFile(uintptr(fd), env)
+	buf, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("cannot read secret %s: %v", name, err)
+	}
+
+	// the fd is gone, don't leave it for any children of ours
+	os.Unsetenv(env)
+	secretCache[name] = string(buf)
+	return string(buf), nil
+}
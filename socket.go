@@ -0,0 +1,95 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:32 (EDT)
+// Function: sockets that stay open across restarts
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// tells the program which fd is which socket: fd=path, one per line
+const socketVar = "_dsockets"
+
+var socketLock sync.Mutex
+var socketCache = make(map[string]net.Listener)
+
+// create the sockets, once
+func (o *opts) openSockets() error {
+
+	for _, path := range o.sockets {
+		if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			// left from last time
+			os.Remove(path)
+		}
+
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return err
+		}
+		f, err := l.(*net.UnixListener).File()
+		if err != nil {
+			return err
+		}
+		// f is a dup, keep the socket file when l is collected
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+		o.sockFiles = append(o.sockFiles, f)
+	}
+
+	return nil
+}
+
+// pass the sockets on to the program, after the stdio files
+func (o *opts) addSockets(pa *os.ProcAttr) {
+
+	if len(o.sockFiles) == 0 {
+		return
+	}
+
+	var fds []string
+	for i, f := range o.sockFiles {
+		fds = append(fds, fmt.Sprintf("%d=%s", len(pa.Files), o.sockets[i]))
+		pa.Files = append(pa.Files, f)
+	}
+	pa.Env = append(pa.Env, socketVar+"="+strings.Join(fds, "\n"))
+}
+
+// GetManagedSocket(path) - in the main program, the listener for the socket created with WithManagedSocket
+func GetManagedSocket(path string) (net.Listener, error) {
+
+	socketLock.Lock()
+	defer socketLock.Unlock()
+
+	if l, ok := socketCache[path]; ok {
+		return l, nil
+	}
+
+	for _, line := range strings.Split(os.Getenv(socketVar), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || kv[1] != path {
+			continue
+		}
+		fd, err := strconv.Atoi(kv[0])
+		if err != nil {
+			break
+		}
+
+		f := os.NewFile(uintptr(fd), path)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		socketCache[path] = l
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("no managed socket %s", path)
+}
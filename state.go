@@ -0,0 +1,67 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:44 (EDT)
+// Function: keep the watcher's history across upgrades
+
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+type watcherState struct {
+	Restarts  int           `json:"restarts"`
+	Crashes   int           `json:"crashes"`
+	LastCrash time.Time     `json:"last_crash,omitempty"`
+	Delay     time.Duration `json:"backoff_delay"` // nanoseconds
+	Tries     int           `json:"backoff_tries"`
+}
+
+// WithStateFile. pick up where the last watcher left off, returns the restart count
+func (w *watcher) loadState() int {
+
+	buf, err := ioutil.ReadFile(w.opt.stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			w.opt.logf(LogLevelWarn, "cannot read state: %v", err)
+		}
+		return 0
+	}
+
+	var st watcherState
+	if err := json.Unmarshal(buf, &st); err != nil {
+		w.opt.logf(LogLevelWarn, "%s: invalid state: %v", w.opt.stateFile, err)
+		return 0
+	}
+
+	w.crashes = st.Crashes
+	w.lastCrash = st.LastCrash
+	w.back.cur = st.Delay
+	w.back.tries = st.Tries
+	w.opt.logf(LogLevelDebug, "loaded state, restarts %d, failures %d", st.Restarts, st.Crashes)
+
+	return st.Restarts
+}
+
+func (w *watcher) saveState() {
+
+	st := watcherState{
+		Restarts:  RespawnCount(),
+		Crashes:   w.crashes,
+		LastCrash: w.lastCrash,
+		Delay:     w.back.cur,
+		Tries:     w.back.tries,
+	}
+
+	buf, err := json.Marshal(st)
+	if err != nil {
+		return
+	}
+
+	if err := writeFileAtomic(w.opt.stateFile, append(buf, '\n'), 0644, false, nil); err != nil {
+		w.opt.logf(LogLevelWarn, "cannot save state: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:52 (EDT)
+// Function: pluggable restart policies
+
+package daemon
+
+import (
+	"sync"
+	"time"
+)
+
+// RestartStrategy decides, after the program fails, whether and when to restart it
+// attempt is the number of failures in a row (see WithMinUptime)
+type RestartStrategy interface {
+	ShouldRestart(st ExitStatus, attempt int) bool
+	Delay(attempt int) time.Duration
+}
+
+// FixedDelayStrategy - restart after Interval, at most MaxRestarts times in a row (0 = forever)
+type FixedDelayStrategy struct {
+	Interval    time.Duration
+	MaxRestarts int
+}
+
+func (s FixedDelayStrategy) ShouldRestart(st ExitStatus, attempt int) bool {
+	return s.MaxRestarts <= 0 || attempt <= s.MaxRestarts
+}
+
+func (s FixedDelayStrategy) Delay(attempt int) time.Duration {
+	return s.Interval
+}
+
+// ExponentialBackoffStrategy - restart after Min, growing by Factor each time, up to Max
+// at most MaxRestarts times in a row (0 = forever)
+type ExponentialBackoffStrategy struct {
+	Min         time.Duration
+	Max         time.Duration
+	Factor      float64
+	MaxRestarts int
+}
+
+func (s ExponentialBackoffStrategy) ShouldRestart(st ExitStatus, attempt int) bool {
+	return s.MaxRestarts <= 0 || attempt <= s.MaxRestarts
+}
+
+func (s ExponentialBackoffStrategy) Delay(attempt int) time.Duration {
+
+	d := float64(s.Min)
+	for i := 1; i < attempt; i++ {
+		d *= s.Factor
+		if d >= float64(s.Max) {
+			return s.Max
+		}
+	}
+	return time.Duration(d)
+}
+
+// CircuitBreakerStrategy - restart after Interval, but if it fails Threshold times within Window,
+// the circuit opens, and we wait ResetTimeout before trying again (half-open). with no ResetTimeout, stop restarting.
+// once a run succeeds (it runs for WithMinUptime, or Window), the circuit closes. Threshold defaults to 5
+type CircuitBreakerStrategy struct {
+	Threshold    int
+	Window       time.Duration
+	Interval     time.Duration
+	ResetTimeout time.Duration
+
+	lock     sync.Mutex
+	clock    Clock // the watcher's, see WithClock
+	failures []time.Time
+	state    int
+	halfOpen time.Time // when we try again
+}
+
+// with no Threshold
+const defaultCircuitThreshold = 5
+
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// the watcher gives us its clock
+func (s *CircuitBreakerStrategy) useClock(c Clock) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clock = c
+}
+
+// lock must be held
+func (s *CircuitBreakerStrategy) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+func (s *CircuitBreakerStrategy) ShouldRestart(st ExitStatus, attempt int) bool {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+
+	if s.state == circuitHalfOpen {
+		if attempt > 0 && now.Sub(s.halfOpen) < s.Window {
+			// still broken
+			s.state = circuitOpen
+			return s.ResetTimeout > 0
+		}
+		// it ran fine for a while
+		s.state = circuitClosed
+		s.failures = nil
+	}
+
+	s.failures = append(s.failures, now)
+
+	// forget the old ones
+	for len(s.failures) > 0 && now.Sub(s.failures[0]) > s.Window {
+		s.failures = s.failures[1:]
+	}
+
+	threshold := s.Threshold
+	if threshold <= 0 {
+		threshold = defaultCircuitThreshold
+	}
+	if len(s.failures) >= threshold {
+		s.state = circuitOpen
+		return s.ResetTimeout > 0
+	}
+	return true
+}
+
+func (s *CircuitBreakerStrategy) Delay(attempt int) time.Duration {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state == circuitOpen {
+		// wait, then try again
+		s.state = circuitHalfOpen
+		s.halfOpen = s.now().Add(s.ResetTimeout)
+		s.failures = nil
+		return s.ResetTimeout
+	}
+	return s.Interval
+}
+
+// CircuitBreakerState() - closed, open (waiting to try again), or half-open (trying again)
+func (s *CircuitBreakerStrategy) CircuitBreakerState() string {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	switch {
+	case s.state == circuitOpen:
+		return "open"
+	case s.state == circuitHalfOpen && now.Before(s.halfOpen):
+		// still waiting
+		return "open"
+	case s.state == circuitHalfOpen && now.Sub(s.halfOpen) < s.Window:
+		return "half-open"
+	}
+	return "closed"
+}
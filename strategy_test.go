@@ -0,0 +1,75 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 02:51 (EDT)
+// Function: test the restart strategies
+
+package daemon_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaw0/go-daemon"
+	"github.com/jaw0/go-daemon/daemontest"
+	"github.com/jaw0/go-daemon/testutil"
+)
+
+func expectCircuit(t *testing.T, cb *daemon.CircuitBreakerStrategy, want string) {
+
+	t.Helper()
+	if st := cb.CircuitBreakerState(); st != want {
+		t.Fatalf("circuit is %s, expected %s", st, want)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	cb := &daemon.CircuitBreakerStrategy{Threshold: 2, Window: time.Minute, Interval: time.Second, ResetTimeout: 30 * time.Second}
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock), daemon.WithRestartStrategy(cb))
+
+	s.Exit(1)
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+	expectCircuit(t, cb, "closed")
+
+	// the second failure within the window opens it
+	s.Exit(1)
+	clock.BlockUntil(1)
+	expectCircuit(t, cb, "open")
+	expectDelay(t, clock, 30*time.Second)
+	s.WaitRestart()
+	expectCircuit(t, cb, "half-open")
+
+	// failing while half-open opens it again
+	s.Exit(1)
+	expectDelay(t, clock, 30*time.Second)
+	s.WaitRestart()
+
+	// running for the window closes it
+	clock.Advance(2 * time.Minute)
+	expectCircuit(t, cb, "closed")
+	s.Exit(1)
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+	expectCircuit(t, cb, "closed")
+}
+
+func TestCircuitBreakerDefaultThreshold(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	cb := &daemon.CircuitBreakerStrategy{Window: time.Minute, Interval: time.Second}
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock), daemon.WithRestartStrategy(cb))
+
+	for i := 0; i < 4; i++ {
+		s.Exit(1)
+		expectDelay(t, clock, time.Second)
+		s.WaitRestart()
+	}
+
+	// no ResetTimeout, the fifth failure stops it
+	s.Exit(1)
+	if code := s.WaitExit(); code != 1 {
+		t.Fatalf("watcher exited %d, expected 1", code)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 01:30 (EDT)
+// Function: systemd integration
+
+package daemon
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// send a notification to systemd, see sd_notify(3)
+func sdNotify(state string) error {
+
+	sock := os.Getenv("NOTIFY_SOCKET")
+	if sock == "" {
+		return errors.New("NOTIFY_SOCKET not set")
+	}
+	if sock[0] == '@' {
+		// abstract namespace
+		sock = "\x00" + sock[1:]
+	}
+
+	c, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	_, err = c.Write([]byte(state))
+	return err
+}
+
+// keep systemd's watchdog happy, so long as the program is running and healthy
+func (w *watcher) systemdWatchdog() {
+
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		w.opt.logf(LogLevelWarn, "systemd watchdog not enabled (no WATCHDOG_USEC)")
+		return
+	}
+
+	tick := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+	defer tick.Stop()
+
+	for range tick.C {
+		if !w.cur.alive() {
+			// let it expire
+			continue
+		}
+		if err := sdNotify("WATCHDOG=1\n"); err != nil {
+			w.opt.logf(LogLevelWarn, "systemd watchdog: %v", err)
+		}
+	}
+}
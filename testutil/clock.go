@@ -0,0 +1,96 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:52 (EDT)
+// Function: fake clock for tests
+
+// helpers for testing code that uses the daemon package
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock implements daemon.Clock, time only moves when Advance is called
+type FakeClock struct {
+	lock     sync.Mutex
+	cond     *sync.Cond
+	now      time.Time
+	sleepers []*sleeper
+}
+
+type sleeper struct {
+	until time.Time
+	wake  chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.lock)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Sleep blocks until the clock has been advanced by d
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// After sends the time once the clock has been advanced by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+
+	ch := make(chan time.Time, 1)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	s := &sleeper{until: c.now.Add(d), wake: ch}
+	c.sleepers = append(c.sleepers, s)
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the clock forward, waking any sleepers that are due
+func (c *FakeClock) Advance(d time.Duration) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var still []*sleeper
+	for _, s := range c.sleepers {
+		if s.until.After(c.now) {
+			still = append(still, s)
+			continue
+		}
+		s.wake <- c.now
+	}
+	c.sleepers = still
+}
+
+// BlockUntil(n) - wait until n goroutines are sleeping (or waiting on After)
+func (c *FakeClock) BlockUntil(n int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for len(c.sleepers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Pending() - how many goroutines are sleeping
+func (c *FakeClock) Pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.sleepers)
+}
@@ -0,0 +1,928 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 00:51 (EDT)
+// Function: watch + restart the program
+
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Process is the watcher's view of the running program
+type Process interface {
+	Pid() int
+	Signal(sig os.Signal) error
+	Wait() (ExitStatus, error)
+}
+
+// ExitStatus describes how the program exited
+type ExitStatus struct {
+	Code   int       // exit code, -1 if terminated by a signal
+	Signal os.Signal // nil unless terminated by a signal
+}
+
+func (e ExitStatus) Exited() bool {
+	return e.Signal == nil
+}
+
+func (e ExitStatus) Success() bool {
+	return e.Signal == nil && e.Code == 0
+}
+
+func (e ExitStatus) String() string {
+	if e.Signal != nil {
+		return "signal: " + e.Signal.String()
+	}
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+type osProcess struct {
+	p *os.Process
+}
+
+func (p osProcess) Pid() int {
+	return p.p.Pid
+}
+
+func (p osProcess) Signal(sig os.Signal) error {
+	return p.p.Signal(sig)
+}
+
+func (p osProcess) Wait() (ExitStatus, error) {
+
+	st, err := p.p.Wait()
+	if err != nil {
+		return ExitStatus{Code: -1}, err
+	}
+
+	es := ExitStatus{Code: st.ExitCode()}
+	if ws, ok := st.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		es.Signal = ws.Signal()
+	}
+	return es, nil
+}
+
+type watcher struct {
+	opt       *opts
+	prog      string
+	start     func() (Process, error)
+	sigchan   chan os.Signal
+	readyc    chan os.Signal          // WithNotifyParent, the program called NotifyReady
+	sigSent   map[os.Signal]time.Time // WithSignalCooldown
+	cur       running
+	limit     *tokenBucket
+	cpu       *cpuThrottle
+	back      *backoff
+	crashes   int
+	lastCrash time.Time
+	restart   restartRequest
+	reapLock  sync.Mutex // WithSubreaper, held while starting, so the program is not mistaken for an orphan
+	stdout    *os.File   // read ends of the program's output, if we are scanning it
+	stderr    *os.File
+	outFile   *os.File // WithStdoutFile, WithStderrFile
+	errFile   *os.File
+	stats     statsBox // WithRuntimeMonitor
+	setup     *os.File // read end of the program's setup pipe
+}
+
+// one run of the program
+type child struct {
+	proc       Process
+	stop       chan struct{} // closed once it exits
+	failed     chan string
+	quit       int32              // don't restart it, the watcher exits with this
+	ready      func()             // called once it passes the startup checks
+	failing    int32              // the last health check failed
+	tail       *lineRing          // recent stderr
+	tailDone   chan struct{}      // closed once stderr is finished
+	persisting map[os.Signal]bool // WithSignalPersistence, already resending
+	notified   chan struct{}      // closed once it calls NotifyReady
+	notifyOnce sync.Once
+}
+
+// the currently running program
+type running struct {
+	lock  sync.Mutex
+	proc  Process
+	child *child
+}
+
+// WatchWith(start, WithOpts...) - run the watch + restart loop in this process, using start to run the program
+// returns once the program exits cleanly. this is the watcher that Ize runs in the background,
+// with start re-executing ourself. see daemontest.
+func WatchWith(start func() (Process, error), optfn ...optFunc) int {
+	opt := newOpts(optfn)
+	if opt.optErr != nil {
+		err := wrapErr(ErrStartFailed, opt.optErr)
+		opt.logf(LogLevelError, "%v", err)
+		return errorCode(err)
+	}
+	prog, _ := os.Executable()
+	w := newWatcher(opt, prog)
+	w.start = start
+	return w.run()
+}
+
+func newWatcher(opt *opts, prog string) *watcher {
+
+	w := &watcher{
+		opt:     opt,
+		prog:    prog,
+		sigchan: make(chan os.Signal, 5),
+		readyc:  make(chan os.Signal, 1),
+		sigSent: make(map[os.Signal]time.Time),
+		back:    opt.newBackoff(),
+	}
+	w.start = func() (Process, error) { return opt.startInNamespace(w.startSelf) }
+
+	if opt.rateLimit > 0 {
+		w.limit = newTokenBucket(opt.rateLimit, opt.clock)
+	}
+	if opt.cpuLimit > 0 {
+		w.cpu = newCPUThrottle(opt.cpuLimit, opt.clock)
+	}
+	w.openOutput()
+
+	return w
+}
+
+func (w *watcher) run() int {
+
+	opt := w.opt
+
+	if opt.pidFile != "" && !opt.deferPidFile {
+		if err := opt.savePidFile(); err != nil && opt.pidExcl {
+			opt.logf(LogLevelError, "%v", err)
+			return errorCode(err)
+		}
+	}
+	if err := opt.openSockets(); err != nil {
+		err = wrapErr(ErrStartFailed, err)
+		opt.logf(LogLevelError, "cannot create socket: %v", err)
+		return errorCode(err)
+	}
+	if opt.pidFile != "" && opt.autoRemovePid {
+		// however we leave
+		defer opt.removePidFileOf(os.Getpid())
+	}
+	opt.audit(auditRecord{Event: "watcher-started"})
+	defer opt.eventsDone()
+
+	if len(opt.watchFiles) > 0 {
+		go opt.onFileChange(opt.watchFiles, func() { w.cur.signal(opt.reloadSignal) })
+	}
+	if opt.updateCheck {
+		go opt.onFileChange([]string{w.prog}, func() { w.restartNow("updated") })
+	}
+	if len(opt.configFiles) > 0 {
+		go opt.onFileChange(opt.configFiles, func() { w.restartNow("config changed") })
+	}
+	if opt.monInterval > 0 {
+		go w.runtimeMonitor()
+	}
+	if opt.metricsAddr != "" {
+		go w.serveMetrics()
+	}
+	if opt.certPath != "" {
+		go w.watchCert()
+	}
+	if opt.sdWatchdog {
+		go w.systemdWatchdog()
+	}
+	for _, sf := range opt.signalFiles {
+		go opt.onFileChange([]string{sf.path}, w.signalFile(sf))
+	}
+	if opt.subreaper || opt.zombieReaper {
+		if err := w.startReaper(opt.subreaper); err != nil {
+			opt.logf(LogLevelWarn, "cannot reap orphans: %v", err)
+		}
+	}
+
+	if opt.startSignal != nil && !w.waitForStart() {
+		opt.audit(auditRecord{Event: "watcher-stopped", Reason: "stopped before starting"})
+		return 0
+	}
+
+	first := 0
+	if opt.stateFile != "" {
+		first = w.loadState()
+		defer w.saveState()
+	}
+
+	for restarts := first; ; restarts++ {
+		atomic.StoreInt32(&respawns, int32(restarts))
+		atomic.StoreInt32(&crashes, int32(w.crashes))
+		if restarts > first {
+			if w.limit != nil {
+				w.limit.wait()
+			}
+			if w.cpu != nil {
+				if d := w.cpu.wait(); d > 0 {
+					opt.logf(LogLevelDebug, "watcher cpu limit, paused %v", d)
+				}
+			}
+			opt.audit(auditRecord{Event: "restart", Restarts: restarts})
+		}
+
+		if opt.dnsCheck != "" {
+			w.waitForDNS()
+		}
+		if opt.redisLock != nil && !w.waitForLock() {
+			opt.audit(auditRecord{Event: "watcher-stopped", Reason: "stopped waiting for lock", Restarts: restarts})
+			return 0
+		}
+
+		// don't confuse a new crash with an old message
+		opt.readExitMessage()
+
+		w.reapLock.Lock()
+		p, err := opt.startWithTimeout(w.start)
+		if err == nil {
+			w.cur.set(p)
+		}
+		w.reapLock.Unlock()
+		if err != nil {
+			opt.logf(LogLevelError, "cannot start %s: %v", w.prog, err)
+			return 2
+		}
+		started := opt.clock.Now()
+		pid := p.Pid()
+		cgroup := ""
+		if opt.cgroupBase != "" {
+			if cgroup, err = opt.newCgroup(pid); err != nil {
+				opt.logf(LogLevelWarn, "%s: cannot create cgroup: %v", w.prog, err)
+			}
+		}
+		if opt.childPidFile != "" {
+			opt.writePidFile(opt.childPidFile, pid, false)
+		}
+		opt.logf(LogLevelDebug, "started %s, pid %d, restarts %d, open fds %d", w.prog, pid, restarts, openFDs())
+		opt.event(EventStarted, pid, 0, restarts, "")
+		opt.audit(auditRecord{Event: "child-started", Pid: pid, Restarts: restarts})
+
+		c := newChild(p)
+		if opt.redisLock != nil {
+			go w.holdLock(c)
+		}
+		if w.wantCrashReport() {
+			c.tail = &lineRing{max: crashTailLines}
+		}
+		deferPid := opt.pidFile != "" && opt.deferPidFile
+		if deferPid || atomic.LoadInt32(&opt.parentPid) != 0 {
+			c.ready = func() {
+				if deferPid {
+					if err := opt.createPidFile(pid); err != nil && opt.pidExcl {
+						opt.logf(LogLevelError, "%v", err)
+						c.killAndQuit(err.Error(), ErrAlreadyRunning)
+						return
+					}
+				}
+				opt.signalParent(opt.waitSig)
+			}
+		}
+		st := w.supervise(c)
+		uptime := opt.clock.Now().Sub(started)
+		if opt.redisLock != nil {
+			if err := opt.redisLock.release(); err != nil {
+				opt.logf(LogLevelWarn, "%s: cannot release lock: %v", w.prog, err)
+			}
+		}
+		if cgroup != "" {
+			opt.removeCgroup(cgroup)
+		}
+		if opt.childPidFile != "" {
+			os.Remove(opt.childPidFile)
+		}
+		if opt.pidFile != "" && opt.deferPidFile {
+			opt.removePidFileOf(pid)
+		}
+		why := c.killedBy()
+		code := st.Code
+		msg := opt.readExitMessage()
+		restartWhy := w.restart.take()
+		quit := atomic.LoadInt32(&c.quit)
+
+		// it could not be set up, and would only fail again
+		setupErr := w.setupFailure()
+		broken := quit == 0 && restartWhy == "" && why == "" && setupErr != ""
+
+		// decide now, so WithCleanupFunc can be told
+		failed := quit == 0 && restartWhy == "" && !broken && (why != "" || !st.Success())
+		giveUp := ""
+		if failed {
+			w.crashed(uptime)
+			giveUp = w.giveUp(st, restarts)
+		}
+
+		if opt.cleanup != nil {
+			opt.cleanup(exitReason(st, why, giveUp))
+		}
+
+		var report *crashReport
+		if !st.Success() && restartWhy == "" && w.wantCrashReport() {
+			c.waitTail()
+			report = w.newCrashReport(c, st, uptime, restarts, why, msg)
+			if opt.crashURL != "" {
+				opt.postCrashReport(report)
+			}
+			if opt.crashDir != "" {
+				opt.writeCrashDump(report)
+			}
+		}
+
+		if quit != 0 {
+			// told to stop
+			opt.logf(LogLevelWarn, "%s: %s, stopping", w.prog, why)
+			opt.event(EventKilled, pid, code, restarts, why)
+			opt.audit(auditRecord{Event: "watcher-stopped", Pid: pid, ExitCode: &code, Reason: why, Restarts: restarts})
+			if opt.pidFile != "" {
+				opt.removePidFileOf(os.Getpid())
+			}
+			return int(quit)
+		}
+
+		if broken {
+			opt.logf(LogLevelError, "%s: %s, stopping", w.prog, setupErr)
+			opt.event(EventStopped, pid, code, restarts, "setup failed")
+			opt.audit(auditRecord{Event: "watcher-stopped", Pid: pid, ExitCode: &code, Reason: "setup failed", Restarts: restarts})
+			if opt.pidFile != "" {
+				opt.removePidFileOf(os.Getpid())
+			}
+			return ErrStartFailed.code
+		}
+
+		if restartWhy != "" {
+			// we stopped it, start it again right away
+			opt.logf(LogLevelInfo, "%s: %s, restarting", w.prog, restartWhy)
+			opt.audit(auditRecord{Event: "restart-scheduled", Pid: pid, ExitCode: &code, Reason: restartWhy, Restarts: restarts})
+			opt.event(EventRestarting, pid, code, restarts+1, restartWhy)
+			continue
+		}
+
+		if why != "" {
+			// killed by the watcher, treat as a crash
+			opt.logf(LogLevelWarn, "%s: %s (failures %d)", w.prog, why, w.crashes)
+			if msg != "" {
+				opt.logf(LogLevelWarn, "%s: %s", w.prog, msg)
+			}
+			opt.audit(auditRecord{Event: "child-exited", Pid: pid, ExitCode: &code, Reason: why, Message: msg, Restarts: restarts})
+			opt.event(EventKilled, pid, code, restarts, why)
+		} else {
+			opt.audit(auditRecord{Event: "child-exited", Pid: pid, ExitCode: &code, Reason: st.String(), Message: msg, Restarts: restarts})
+
+			if st.Success() {
+				// done
+				opt.event(EventStopped, pid, 0, restarts, "")
+				opt.audit(auditRecord{Event: "watcher-stopped", Pid: pid, Restarts: restarts})
+				if opt.pidFile != "" {
+					opt.removePidFileOf(os.Getpid())
+				}
+				if opt.afterExit != nil {
+					opt.afterExit()
+				}
+				return 0
+			}
+
+			if msg != "" {
+				opt.logf(LogLevelWarn, "%s: %s: %s", w.prog, st, msg)
+			} else {
+				opt.logf(LogLevelWarn, "%s: %s", w.prog, st)
+			}
+			opt.event(EventCrashed, pid, code, restarts, msg)
+		}
+
+		if len(opt.afterCrash) > 0 {
+			// keep the reaper away from it
+			w.reapLock.Lock()
+			opt.runAfterCrash(pid, st)
+			w.reapLock.Unlock()
+		}
+
+		if giveUp != "" {
+			if giveUp == "restart strategy" {
+				opt.logf(LogLevelError, "%s: failed %d times, giving up", w.prog, w.crashes)
+			} else {
+				opt.logf(LogLevelError, "%s: restarted %d times, giving up", w.prog, restarts)
+			}
+			opt.event(EventStopped, pid, code, restarts, giveUp)
+			opt.audit(auditRecord{Event: "watcher-stopped", Pid: pid, ExitCode: &code, Reason: giveUp, Restarts: restarts})
+			if opt.pidFile != "" {
+				opt.removePidFileOf(os.Getpid())
+			}
+			return 1
+		}
+
+		var delay time.Duration
+		switch {
+		case opt.strategy != nil:
+			delay = opt.strategy.Delay(w.crashes)
+		case why != "" || st.Exited():
+			// if it was killed by someone else, restart right away
+			delay = w.back.next(uptime)
+		}
+		opt.logf(LogLevelDebug, "pid %d ran for %s, restarting in %s", pid, uptime, delay)
+		opt.audit(auditRecord{Event: "restart-scheduled", Pid: pid, Restarts: restarts, Delay: delay.String()})
+		opt.event(EventRestarting, pid, code, restarts+1, "")
+		if opt.hookURL != "" && report != nil {
+			opt.postRestartHook(report)
+		}
+		opt.clock.Sleep(delay)
+	}
+}
+
+// with WithStartAfterSignal, hold until we are told to start
+// returns false if we are told to stop instead
+func (w *watcher) waitForStart() bool {
+
+	w.opt.logf(LogLevelInfo, "%s: waiting for %v to start", w.prog, w.opt.startSignal)
+
+	for n := range w.sigchan {
+		switch n {
+		case w.opt.startSignal:
+			return true
+		case syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
+			return false
+		}
+	}
+	return false
+}
+
+// WithSignalFile. when the file appears, signal the program and remove it
+func (w *watcher) signalFile(sf signalFile) func() {
+	return func() {
+		if _, err := os.Stat(sf.path); err != nil {
+			return
+		}
+		w.opt.logf(LogLevelDebug, "%s: sending %v", sf.path, sf.sig)
+		w.cur.signal(sf.sig)
+		os.Remove(sf.path)
+	}
+}
+
+// after a failure, should we stop restarting it? and why
+func (w *watcher) giveUp(st ExitStatus, restarts int) string {
+
+	switch {
+	case w.opt.strategy != nil && !w.opt.strategy.ShouldRestart(st, w.crashes):
+		return "restart strategy"
+	case w.opt.respawnLimit > 0 && restarts >= w.opt.respawnLimit:
+		return "respawn limit reached"
+	}
+	return ""
+}
+
+// WithCleanupFunc. why did it exit?
+func exitReason(st ExitStatus, why, giveUp string) ExitReason {
+
+	switch {
+	case why != "":
+		return ExitReasonKilled
+	case st.Success():
+		return ExitReasonClean
+	case giveUp != "":
+		return ExitReasonMaxRestarts
+	case st.Signal != nil:
+		return ExitReasonSignal
+	}
+	return ExitReasonCrash
+}
+
+// stop the program (SIGTERM), so we can restart it
+// if it is not running, the next run starts with the change anyway
+func (w *watcher) restartNow(why string) {
+
+	w.cur.lock.Lock()
+	defer w.cur.lock.Unlock()
+
+	if w.cur.proc == nil {
+		return
+	}
+	w.restart.set(why)
+	w.cur.proc.Signal(syscall.SIGTERM)
+}
+
+// count crashes. with WithMinUptime, a program that ran long enough was not crash looping
+func (w *watcher) crashed(uptime time.Duration) {
+
+	w.lastCrash = w.opt.clock.Now()
+	if w.opt.minUptime > 0 && uptime >= w.opt.minUptime {
+		w.crashes = 0
+		return
+	}
+	w.crashes++
+}
+
+// run another copy of ourself, as the main program
+func (w *watcher) startSelf() (Process, error) {
+
+	os.Setenv(ENVVAR, "2")
+	dn, _ := os.OpenFile(os.DevNull, os.O_RDWR, 0666)
+	defer dn.Close()
+	env := append(w.opt.environ(),
+		fmt.Sprintf("%s=%d", respawnVar, RespawnCount()),
+		fmt.Sprintf("%s=%d", crashVar, RestartCount()))
+	pa := &os.ProcAttr{Files: []*os.File{dn, dn, os.Stderr}, Env: env, Sys: w.opt.sysProcAttr()}
+	if w.opt.foreground {
+		pa.Files[1] = os.Stdout
+	} else if !w.opt.keepStderr {
+		pa.Files[2] = dn
+	}
+	if w.outFile != nil {
+		pa.Files[1] = w.outFile
+	}
+	if w.errFile != nil {
+		pa.Files[2] = w.errFile
+	}
+
+	// write ends, ours are closed once the program has them
+	var pipes []*os.File
+	defer func() {
+		for _, f := range pipes {
+			f.Close()
+		}
+	}()
+
+	if w.opt.stdinPipe != nil {
+		// kept open, for the next run
+		pa.Files[0] = w.opt.stdinPipe
+	}
+	if w.opt.stdinFile != "" {
+		// from the start, each time
+		f, err := os.Open(w.opt.stdinFile)
+		if err != nil {
+			return nil, err
+		}
+		pa.Files[0] = f
+		pipes = append(pipes, f)
+	}
+
+	if w.opt.wantStdoutPipe() {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		w.stdout = pr
+		pa.Files[1] = pw
+		pipes = append(pipes, pw)
+	}
+	if w.opt.wantStderrPipe() {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			w.closeOutput()
+			return nil, err
+		}
+		w.stderr = pr
+		pa.Files[2] = pw
+		pipes = append(pipes, pw)
+	}
+
+	w.opt.addSockets(pa)
+	w.opt.passParent(pa)
+	if err := w.opt.addPorts(pa); err != nil {
+		w.closeOutput()
+		return nil, err
+	}
+	ends, err := w.opt.addSecrets(pa)
+	if err != nil {
+		w.closeOutput()
+		return nil, err
+	}
+	pipes = append(pipes, ends...)
+
+	if w.opt.needsSetup() {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			w.closeOutput()
+			return nil, err
+		}
+		pa.Env = append(pa.Env, fmt.Sprintf("%s=%d", setupVar, len(pa.Files)))
+		pa.Files = append(pa.Files, pw)
+		pipes = append(pipes, pw)
+		w.setup = pr
+	}
+
+	p, err := startProcess(w.prog, w.opt.mainArgs(), pa)
+	if err != nil {
+		w.closeOutput()
+		w.setupFailure()
+	}
+	return p, err
+}
+
+// after it exits, did the program tell us it could not be set up?
+func (w *watcher) setupFailure() string {
+
+	if w.setup == nil {
+		return ""
+	}
+	// the program closes its end once it is set up, or it exits
+	buf, _ := ioutil.ReadAll(w.setup)
+	w.setup.Close()
+	w.setup = nil
+	return string(buf)
+}
+
+func startProcess(prog string, args []string, pa *os.ProcAttr) (Process, error) {
+
+	p, err := os.StartProcess(prog, args, pa)
+	if err != nil {
+		return nil, err
+	}
+	return osProcess{p}, nil
+}
+
+// with WithForkTimeout, don't hang forever in start
+func (o *opts) startWithTimeout(start func() (Process, error)) (Process, error) {
+
+	if o.forkTimeout <= 0 {
+		return start()
+	}
+
+	type result struct {
+		p   Process
+		err error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		p, err := start()
+		done <- result{p, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.p, r.err
+	case <-time.After(o.forkTimeout):
+		o.fatal(wrapErrf(ErrTimeout, "could not start the program within %v", o.forkTimeout))
+		return nil, ErrTimeout
+	}
+}
+
+func (w *watcher) closeOutput() {
+
+	for _, f := range []*os.File{w.stdout, w.stderr} {
+		if f != nil {
+			f.Close()
+		}
+	}
+	w.stdout, w.stderr = nil, nil
+}
+
+// wait for the program to exit, passing signals along and keeping an eye on it
+// returns the reason, if the watcher killed it
+func (w *watcher) supervise(c *child) ExitStatus {
+
+	p := c.proc
+	w.cur.set(p)
+	w.cur.setChild(c)
+	defer w.cur.setChild(nil)
+	defer w.cur.set(nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-w.readyc:
+				w.opt.logf(LogLevelDebug, "pid %d is ready", p.Pid())
+				if c.notifiedReady() && w.opt.notifyParent && c.ready != nil {
+					// it is ready when it says so
+					c.ready()
+				}
+			case n := <-w.sigchan:
+				if w.coolingDown(n) {
+					w.opt.logf(LogLevelDebug, "ignoring repeated %v", n)
+					continue
+				}
+				// pass the signal on through to the running program
+				w.opt.logf(LogLevelDebug, "passing %v to pid %d", n, p.Pid())
+				p.Signal(n)
+				if sp, ok := w.opt.sigPersist[n]; ok && !c.persisting[n] {
+					c.persisting[n] = true
+					go w.persistSignal(c, n, sp)
+				}
+				if w.opt.sigNotify != nil {
+					select {
+					case w.opt.sigNotify <- n:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	if c.ready != nil && !w.opt.wantStartupCheck() && !w.opt.notifyParent {
+		c.ready()
+	}
+	if w.opt.maxStartup > 0 {
+		go w.opt.startupDeadline(c)
+	}
+
+	if w.opt.wantMonitor() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.opt.monitor(c)
+		}()
+	}
+
+	// not waited for, grandchildren may keep them open
+	if w.stdout != nil {
+		go w.scanStdout(w.stdout, c)
+		w.stdout = nil
+	}
+	if w.stderr != nil {
+		go w.scanStderr(w.stderr, c)
+		w.stderr = nil
+	} else {
+		close(c.tailDone)
+	}
+
+	st, _ := p.Wait()
+	close(c.stop)
+	wg.Wait()
+
+	return st
+}
+
+// with WithSignalCooldown, was the same signal just sent?
+func (w *watcher) coolingDown(sig os.Signal) bool {
+
+	d, ok := w.opt.sigCooldown[sig]
+	if !ok {
+		return false
+	}
+
+	now := w.opt.clock.Now()
+	if last, ok := w.sigSent[sig]; ok && now.Sub(last) < d {
+		return true
+	}
+	w.sigSent[sig] = now
+	return false
+}
+
+// WithSignalPersistence. keep sending the signal until it exits, then give up and kill it
+func (w *watcher) persistSignal(c *child, sig os.Signal, sp signalPersist) {
+
+	for i := 0; ; i++ {
+		select {
+		case <-c.stop:
+			return
+		case <-w.opt.clock.After(sp.interval):
+		}
+
+		if i >= sp.max {
+			why := fmt.Sprintf("ignored %v", sig)
+			switch sig {
+			case syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
+				// it was supposed to stop
+				c.killAndQuit(why, ErrKilled)
+			default:
+				c.kill(why)
+			}
+			return
+		}
+		w.opt.logf(LogLevelDebug, "resending %v to pid %d", sig, c.proc.Pid())
+		c.proc.Signal(sig)
+	}
+}
+
+func newChild(p Process) *child {
+	return &child{
+		proc:       p,
+		stop:       make(chan struct{}),
+		failed:     make(chan string, 1),
+		tailDone:   make(chan struct{}),
+		persisting: make(map[os.Signal]bool),
+		notified:   make(chan struct{}),
+	}
+}
+
+// the program called NotifyReady. true the first time
+func (c *child) notifiedReady() (first bool) {
+	c.notifyOnce.Do(func() {
+		close(c.notified)
+		first = true
+	})
+	return first
+}
+
+// give the stderr reader a moment to catch up with what the program said on the way out
+func (c *child) waitTail() {
+	select {
+	case <-c.tailDone:
+	case <-time.After(time.Second):
+	}
+}
+
+// kill the program, unless it has already exited on its own
+func (c *child) kill(why string) {
+
+	select {
+	case <-c.stop:
+		return
+	default:
+	}
+
+	select {
+	case c.failed <- why:
+		c.proc.Signal(os.Kill)
+	default:
+		// already being killed
+	}
+}
+
+// kill the program, and don't restart it. the watcher exits with kind's code
+func (c *child) killAndQuit(why string, kind *Error) {
+	atomic.StoreInt32(&c.quit, int32(kind.code))
+	c.kill(why)
+}
+
+// wrap a health check, to remember how it went
+func (c *child) healthCheck(check func() bool) func() bool {
+	return func() bool {
+		if check() {
+			atomic.StoreInt32(&c.failing, 0)
+			return true
+		}
+		atomic.StoreInt32(&c.failing, 1)
+		return false
+	}
+}
+
+// the reason, if the watcher killed it
+func (c *child) killedBy() string {
+	select {
+	case why := <-c.failed:
+		return why
+	default:
+		return ""
+	}
+}
+
+// why the watcher stopped the program, to restart it
+type restartRequest struct {
+	lock sync.Mutex
+	why  string
+}
+
+func (r *restartRequest) set(why string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.why = why
+}
+
+func (r *restartRequest) take() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	why := r.why
+	r.why = ""
+	return why
+}
+
+func (r *running) set(p Process) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.proc = p
+}
+
+func (r *running) setChild(c *child) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.child = c
+}
+
+// is the program running, and passing its health checks?
+func (r *running) alive() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.proc != nil && r.child != nil && atomic.LoadInt32(&r.child.failing) == 0
+}
+
+func (r *running) pid() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.proc == nil {
+		return 0
+	}
+	return r.proc.Pid()
+}
+
+func (r *running) signal(sig os.Signal) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.proc != nil {
+		r.proc.Signal(sig)
+	}
+}
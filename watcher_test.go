@@ -0,0 +1,169 @@
+// Copyright (c) 2026
+// Author: Jeff Weisberg <jaw @ tcp4me.com>
+// Created: 2026-Oct-14 02:30 (EDT)
+// Function: test the watch + restart loop
+
+package daemon_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jaw0/go-daemon"
+	"github.com/jaw0/go-daemon/daemontest"
+	"github.com/jaw0/go-daemon/testutil"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// wait for the watcher to sleep for exactly d, then let it continue
+func expectDelay(t *testing.T, clock *testutil.FakeClock, d time.Duration) {
+
+	t.Helper()
+	clock.BlockUntil(1)
+	clock.Advance(d - time.Millisecond)
+	if clock.Pending() != 1 {
+		t.Fatalf("restarted before %v", d)
+	}
+	clock.Advance(time.Millisecond)
+}
+
+func TestCleanExit(t *testing.T) {
+
+	s := daemontest.SimulateDaemon(t)
+	s.Exit(0)
+
+	if code := s.WaitExit(); code != 0 {
+		t.Fatalf("watcher exited %d, expected 0", code)
+	}
+}
+
+func TestRestartAfterCrash(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock), daemon.WithRestartDelay(3*time.Second))
+
+	pid := s.Pid()
+	s.Exit(1)
+	expectDelay(t, clock, 3*time.Second)
+	s.WaitRestart()
+
+	if s.Pid() == pid {
+		t.Fatalf("pid %d was not restarted", pid)
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock),
+		daemon.WithRestartBackoff(time.Second, 4*time.Second, 2))
+
+	for _, d := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		s.Exit(1)
+		expectDelay(t, clock, d)
+		s.WaitRestart()
+	}
+
+	// running for longer than max starts over
+	clock.Advance(5 * time.Second)
+	s.Exit(1)
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+}
+
+func TestRespawnLimit(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock),
+		daemon.WithRestartDelay(time.Second), daemon.WithRespawnLimit(2))
+
+	for i := 0; i < 2; i++ {
+		s.Exit(1)
+		expectDelay(t, clock, time.Second)
+		s.WaitRestart()
+	}
+	s.Exit(1)
+
+	if code := s.WaitExit(); code != 1 {
+		t.Fatalf("watcher exited %d, expected 1", code)
+	}
+}
+
+func TestStrategyGivesUp(t *testing.T) {
+
+	reasons := make(chan daemon.ExitReason, 10)
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock),
+		daemon.WithRestartStrategy(daemon.FixedDelayStrategy{Interval: time.Second, MaxRestarts: 1}),
+		daemon.WithCleanupFunc(func(r daemon.ExitReason) { reasons <- r }))
+
+	s.Exit(1)
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+	s.Exit(1)
+
+	if code := s.WaitExit(); code != 1 {
+		t.Fatalf("watcher exited %d, expected 1", code)
+	}
+	for _, want := range []daemon.ExitReason{daemon.ExitReasonCrash, daemon.ExitReasonMaxRestarts} {
+		if r := <-reasons; r != want {
+			t.Fatalf("cleanup got %v, expected %v", r, want)
+		}
+	}
+}
+
+// a config change while the program is not running must not be taken for the next exit
+func TestConfigChangeWhileStopped(t *testing.T) {
+
+	conf := filepath.Join(t.TempDir(), "conf")
+	ioutil.WriteFile(conf, []byte("a"), 0644)
+
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock),
+		daemon.WithRestartDelay(time.Second), daemon.WithRestartOnConfigChange(conf))
+
+	// once we know it is watching
+	for ok := false; !ok; {
+		ioutil.WriteFile(conf, []byte("b"), 0644)
+		time.Sleep(200 * time.Millisecond)
+		for _, sig := range s.Signals() {
+			ok = ok || sig == syscall.SIGTERM
+		}
+	}
+	s.Exit(0)
+	s.WaitRestart()
+
+	s.Exit(1)
+	clock.BlockUntil(1)
+	ioutil.WriteFile(conf, []byte("c"), 0644)
+	time.Sleep(300 * time.Millisecond)
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+
+	s.Exit(0)
+	done := make(chan int, 1)
+	go func() { done <- s.WaitExit() }()
+	select {
+	case code := <-done:
+		if code != 0 {
+			t.Fatalf("watcher exited %d, expected 0", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("restarted after a clean exit")
+	}
+}
+
+func TestRestartRequest(t *testing.T) {
+
+	clock := testutil.NewFakeClock(epoch)
+	s := daemontest.SimulateDaemon(t, daemon.WithClock(clock), daemon.WithRestartDelay(time.Second))
+
+	// RequestRestart
+	s.Exit(daemon.ExitRestart)
+	expectDelay(t, clock, time.Second)
+	s.WaitRestart()
+}